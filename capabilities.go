@@ -0,0 +1,111 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+)
+
+// Feature names an optional solver command, or family of commands.
+type Feature string
+
+const (
+	FeatureCheckSatAssuming Feature = "check-sat-assuming"
+	FeatureUnsatCores       Feature = "unsat-cores"
+	FeatureProofs           Feature = "proofs"
+	FeatureInterpolation    Feature = "interpolation"
+)
+
+// commandFeatures maps the commands that only some solvers
+// implement to the feature they belong to.
+var commandFeatures = map[string]Feature{
+	"check-sat-assuming":    FeatureCheckSatAssuming,
+	"get-unsat-assumptions": FeatureCheckSatAssuming,
+	"get-unsat-core":        FeatureUnsatCores,
+	"get-proof":             FeatureProofs,
+	"get-interpolant":       FeatureInterpolation,
+	"get-interpolants":      FeatureInterpolation,
+}
+
+// Capabilities describes what a solver supports, so that callers can
+// pick an encoding it will accept.  A nil Logics, Theories or
+// Features means that the solver's support is unknown, and it is
+// trusted to handle whatever it is asked.
+type Capabilities struct {
+	Name     string
+	Version  string
+	Logics   []string
+	Theories []Theory
+	Features []Feature
+}
+
+func (c *Capabilities) SupportsLogic(logic string) bool {
+	if c.Logics == nil {
+		return true
+	}
+	for _, l := range c.Logics {
+		if l == logic {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Capabilities) SupportsTheory(th Theory) bool {
+	if c.Theories == nil {
+		return true
+	}
+	for _, t := range c.Theories {
+		if t == th {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Capabilities) Supports(f Feature) bool {
+	if c.Features == nil {
+		return true
+	}
+	for _, feature := range c.Features {
+		if feature == f {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTheories returns an error wrapping ErrUnsupported if any of
+// theories isn't supported.
+func (c *Capabilities) CheckTheories(theories []Theory) error {
+	for _, th := range theories {
+		if !c.SupportsTheory(th) {
+			return fmt.Errorf("%s doesn't implement the %s theory: %w", c.name(), th, ErrUnsupported)
+		}
+	}
+	return nil
+}
+
+// CheckCommand returns an error wrapping ErrUnsupported if cmd is a
+// command, or sets a logic, that isn't supported.
+func (c *Capabilities) CheckCommand(cmd Sexp) error {
+	name := commandName(cmd)
+	if f, ok := commandFeatures[name]; ok && !c.Supports(f) {
+		return fmt.Errorf("%s doesn't implement %s: %w", c.name(), name, ErrUnsupported)
+	}
+	if list, ok := cmd.(*SList); ok && name == "set-logic" && len(list.List) == 2 {
+		if logic, ok := list.List[1].(*SSymbol); ok && !c.SupportsLogic(logic.Symbol) {
+			return fmt.Errorf("%s doesn't implement the %s logic: %w", c.name(), logic.Symbol, ErrUnsupported)
+		}
+	}
+	return nil
+}
+
+func (c *Capabilities) name() string {
+	if c.Name == "" {
+		return "solver"
+	}
+	return c.Name
+}
@@ -0,0 +1,788 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Eval evaluates t, giving each constant its value in model, and
+// returns the result as a literal: true or false, an Int, a BitVec,
+// or a Real written as a Decimal or a (possibly negated) quotient of
+// Decimals, as solvers write them in models.  The core, Ints, Reals
+// and BitVecs theories are supported; anything else, including
+// quantifiers and uninterpreted functions, is an error.
+//
+// Where SMT-LIB leaves a result unspecified, like division of an Int
+// or Real by zero, Eval returns an error rather than guess how the
+// solver chose to interpret it.
+func Eval(t Term, model map[string]Term) (Term, error) {
+	e := &evaluator{
+		model:  model,
+		values: make(map[string]value),
+		active: make(map[string]bool),
+	}
+	v, err := e.eval(t)
+	if err != nil {
+		return nil, err
+	}
+	return v.term()
+}
+
+type valueKind int
+
+const (
+	boolKind valueKind = iota
+	intKind
+	realKind
+	bvKind
+)
+
+var kindNames = [...]string{"Bool", "Int", "Real", "BitVec"}
+
+type value struct {
+	kind  valueKind
+	b     bool
+	n     *big.Int // an Int, or a BitVec's unsigned value
+	r     *big.Rat
+	width int64
+}
+
+func boolValue(b bool) value {
+	return value{kind: boolKind, b: b}
+}
+
+func intValue(n *big.Int) value {
+	return value{kind: intKind, n: n}
+}
+
+func realValue(r *big.Rat) value {
+	return value{kind: realKind, r: r}
+}
+
+// bvValue returns the BitVec of width w with value n, wrapped to fit.
+func bvValue(n *big.Int, w int64) value {
+	return value{kind: bvKind, n: new(big.Int).And(n, bvMask(w)), width: w}
+}
+
+func bvMask(w int64) *big.Int {
+	one := big.NewInt(1)
+	return new(big.Int).Sub(new(big.Int).Lsh(one, uint(w)), one)
+}
+
+// signed returns v's value as a two's complement integer.
+func (v value) signed() *big.Int {
+	if v.n.Bit(int(v.width-1)) == 0 {
+		return v.n
+	}
+	return new(big.Int).Sub(v.n, new(big.Int).Lsh(big.NewInt(1), uint(v.width)))
+}
+
+// rat returns an Int or Real as a Real.
+func (v value) rat() *big.Rat {
+	if v.kind == intKind {
+		return new(big.Rat).SetInt(v.n)
+	}
+	return v.r
+}
+
+func (v value) term() (Term, error) {
+	switch v.kind {
+	case boolKind:
+		return NewBool(v.b), nil
+	case intKind:
+		return bigIntTerm(v.n), nil
+	case realKind:
+		num := new(big.Int).Abs(v.r.Num())
+		var t Term = &Decimal{num.String() + ".0"}
+		if !v.r.IsInt() {
+			t = &App{"/", []Term{t, &Decimal{v.r.Denom().String() + ".0"}}}
+		}
+		if v.r.Sign() < 0 {
+			t = &App{"-", []Term{t}}
+		}
+		return t, nil
+	case bvKind:
+		if v.width > 64 {
+			return &IndexedApp{Identifier("bv" + v.n.String()), []int64{v.width}, nil}, nil
+		}
+		return &BitVec{int64(v.n.Uint64()), v.width}, nil
+	}
+	panic("unknown value kind")
+}
+
+func (v value) String() string {
+	t, err := v.term()
+	if err != nil {
+		return "?"
+	}
+	return TermToSexp(t).String()
+}
+
+type binding struct {
+	id Identifier
+	v  value
+}
+
+type evaluator struct {
+	model  map[string]Term
+	values map[string]value // of model constants, once evaluated
+	active map[string]bool  // model constants being evaluated
+	env    []binding        // let bindings, innermost last
+}
+
+func (e *evaluator) eval(term Term) (value, error) {
+	switch t := term.(type) {
+	case *Int:
+		return intValue(big.NewInt(t.Int)), nil
+	case *BigInt:
+		return intValue(t.Int), nil
+	case *Decimal:
+		r, ok := new(big.Rat).SetString(t.Decimal)
+		if !ok {
+			return value{}, fmt.Errorf("bad decimal %s", t.Decimal)
+		}
+		return realValue(r), nil
+	case *BitVec:
+		if t.Width <= 0 {
+			return value{}, fmt.Errorf("bad BitVec width %d", t.Width)
+		}
+		return bvValue(new(big.Int).SetUint64(uint64(t.Value)), t.Width), nil
+	case *Const:
+		return e.lookup(t.Id)
+	case *Let:
+		v, err := e.eval(t.Value)
+		if err != nil {
+			return value{}, err
+		}
+		e.env = append(e.env, binding{t.Id, v})
+		defer func() { e.env = e.env[:len(e.env)-1] }()
+		return e.eval(t.In)
+	case *App:
+		return e.app(t)
+	case *IndexedApp:
+		return e.indexedApp(t)
+	case *Annotated:
+		return e.eval(t.Term)
+	}
+	if term == nil {
+		return value{}, fmt.Errorf("can't evaluate nil term")
+	}
+	return value{}, fmt.Errorf("can't evaluate %s", TermToSexp(term))
+}
+
+func (e *evaluator) lookup(id Identifier) (value, error) {
+	for i := len(e.env) - 1; i >= 0; i-- {
+		if e.env[i].id == id {
+			return e.env[i].v, nil
+		}
+	}
+	switch id {
+	case "true":
+		return boolValue(true), nil
+	case "false":
+		return boolValue(false), nil
+	}
+
+	name := string(id)
+	if v, ok := e.values[name]; ok {
+		return v, nil
+	}
+	t, ok := e.model[name]
+	if !ok {
+		return value{}, fmt.Errorf("no value for %s in model", id)
+	}
+	if t == nil {
+		return value{}, fmt.Errorf("nil value for %s in model", id)
+	}
+	if e.active[name] {
+		return value{}, fmt.Errorf("value of %s refers to itself", id)
+	}
+	// model values are closed, so evaluate them outside any lets
+	e.active[name] = true
+	env := e.env
+	e.env = nil
+	v, err := e.eval(t)
+	e.env = env
+	delete(e.active, name)
+	if err != nil {
+		return value{}, fmt.Errorf("%s: %s", id, err)
+	}
+	e.values[name] = v
+	return v, nil
+}
+
+// evalArgs evaluates args, which must all be of kind.
+func (e *evaluator) evalArgs(op Identifier, args []Term, kinds ...valueKind) ([]value, error) {
+	vs := make([]value, len(args))
+	for i, arg := range args {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		ok := false
+		for _, k := range kinds {
+			ok = ok || v.kind == k
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: unexpected %s argument %s", op, kindNames[v.kind], v)
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
+
+func (e *evaluator) app(t *App) (value, error) {
+	switch t.Id {
+	case "and", "or", "=>", "ite":
+		return e.lazy(t)
+	case "not", "xor":
+		return e.boolOp(t)
+	case "=", "distinct":
+		return e.equality(t)
+	case "+", "-", "*", "/", "div", "mod", "abs", "<", "<=", ">", ">=", "to_real", "to_int", "is_int":
+		return e.arith(t)
+	}
+	if strings.HasPrefix(string(t.Id), "bv") || t.Id == "concat" {
+		return e.bv(t)
+	}
+	return value{}, fmt.Errorf("can't evaluate %s", t.Id)
+}
+
+// lazy evaluates the operators whose arguments aren't all needed,
+// so that unneeded ones we can't evaluate don't matter.
+func (e *evaluator) lazy(t *App) (value, error) {
+	arg := func(i int) (bool, error) {
+		vs, err := e.evalArgs(t.Id, t.Args[i:i+1], boolKind)
+		if err != nil {
+			return false, err
+		}
+		return vs[0].b, nil
+	}
+	switch t.Id {
+	case "and", "or":
+		short := t.Id == "or"
+		for i := range t.Args {
+			b, err := arg(i)
+			if err != nil {
+				return value{}, err
+			}
+			if b == short {
+				return boolValue(short), nil
+			}
+		}
+		return boolValue(!short), nil
+	case "=>":
+		// right associative: all but the last must hold for the
+		// last to matter
+		if len(t.Args) < 2 {
+			return value{}, fmt.Errorf("=> needs at least 2 arguments")
+		}
+		for i := range t.Args[:len(t.Args)-1] {
+			b, err := arg(i)
+			if err != nil {
+				return value{}, err
+			}
+			if !b {
+				return boolValue(true), nil
+			}
+		}
+		b, err := arg(len(t.Args) - 1)
+		return boolValue(b), err
+	default: // ite
+		if len(t.Args) != 3 {
+			return value{}, fmt.Errorf("ite needs 3 arguments")
+		}
+		b, err := arg(0)
+		if err != nil {
+			return value{}, err
+		}
+		if b {
+			return e.eval(t.Args[1])
+		}
+		return e.eval(t.Args[2])
+	}
+}
+
+func (e *evaluator) boolOp(t *App) (value, error) {
+	vs, err := e.evalArgs(t.Id, t.Args, boolKind)
+	if err != nil {
+		return value{}, err
+	}
+	if t.Id == "not" {
+		if len(vs) != 1 {
+			return value{}, fmt.Errorf("not needs 1 argument")
+		}
+		return boolValue(!vs[0].b), nil
+	}
+	b := false
+	for _, v := range vs {
+		b = b != v.b
+	}
+	return boolValue(b), nil
+}
+
+func equal(a, b value) (bool, error) {
+	switch {
+	case a.kind == boolKind && b.kind == boolKind:
+		return a.b == b.b, nil
+	case a.kind == bvKind && b.kind == bvKind:
+		if a.width != b.width {
+			return false, fmt.Errorf("comparing BitVecs of widths %d and %d", a.width, b.width)
+		}
+		return a.n.Cmp(b.n) == 0, nil
+	case isNumeric(a) && isNumeric(b):
+		return a.rat().Cmp(b.rat()) == 0, nil
+	}
+	return false, fmt.Errorf("comparing %s with %s", kindNames[a.kind], kindNames[b.kind])
+}
+
+func (e *evaluator) equality(t *App) (value, error) {
+	vs, err := e.evalArgs(t.Id, t.Args, boolKind, intKind, realKind, bvKind)
+	if err != nil {
+		return value{}, err
+	}
+	if len(vs) < 2 {
+		return value{}, fmt.Errorf("%s needs at least 2 arguments", t.Id)
+	}
+	for i := range vs {
+		// = is chainable, distinct pairwise
+		others := vs[i+1:]
+		if t.Id == "=" {
+			others = vs[i+1:]
+			if len(others) > 1 {
+				others = others[:1]
+			}
+		}
+		for _, w := range others {
+			eq, err := equal(vs[i], w)
+			if err != nil {
+				return value{}, err
+			}
+			if t.Id == "=" && !eq {
+				return boolValue(false), nil
+			}
+			if t.Id == "distinct" && eq {
+				return boolValue(false), nil
+			}
+		}
+	}
+	return boolValue(true), nil
+}
+
+func isNumeric(v value) bool {
+	return v.kind == intKind || v.kind == realKind
+}
+
+func (e *evaluator) arith(t *App) (value, error) {
+	vs, err := e.evalArgs(t.Id, t.Args, intKind, realKind)
+	if err != nil {
+		return value{}, err
+	}
+	if len(vs) == 0 {
+		return value{}, fmt.Errorf("%s needs arguments", t.Id)
+	}
+	isInt := true
+	for _, v := range vs {
+		isInt = isInt && v.kind == intKind
+	}
+	arity := func(n int) error {
+		if len(vs) != n {
+			return fmt.Errorf("%s needs %d arguments", t.Id, n)
+		}
+		return nil
+	}
+
+	switch t.Id {
+	case "<", "<=", ">", ">=":
+		for i := 0; i+1 < len(vs); i++ {
+			c := vs[i].rat().Cmp(vs[i+1].rat())
+			ok := map[Identifier]bool{"<": c < 0, "<=": c <= 0, ">": c > 0, ">=": c >= 0}[t.Id]
+			if !ok {
+				return boolValue(false), nil
+			}
+		}
+		return boolValue(true), nil
+	case "div", "mod":
+		if !isInt {
+			return value{}, fmt.Errorf("%s of a Real", t.Id)
+		}
+		if t.Id == "mod" {
+			if err := arity(2); err != nil {
+				return value{}, err
+			}
+		}
+		n := new(big.Int).Set(vs[0].n)
+		for _, d := range vs[1:] {
+			if d.n.Sign() == 0 {
+				return value{}, fmt.Errorf("%s by zero", t.Id)
+			}
+			// big.Int's DivMod is Euclidean, as SMT-LIB's are
+			q, m := new(big.Int).DivMod(n, d.n, new(big.Int))
+			if t.Id == "div" {
+				n = q
+			} else {
+				n = m
+			}
+		}
+		return intValue(n), nil
+	case "abs":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		if isInt {
+			return intValue(new(big.Int).Abs(vs[0].n)), nil
+		}
+		return realValue(new(big.Rat).Abs(vs[0].r)), nil
+	case "to_real":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		return realValue(vs[0].rat()), nil
+	case "to_int", "is_int":
+		if err := arity(1); err != nil {
+			return value{}, err
+		}
+		r := vs[0].rat()
+		floor := new(big.Int).Div(r.Num(), r.Denom()) // Euclidean, so floor for positive denominators
+		if t.Id == "is_int" {
+			return boolValue(r.IsInt()), nil
+		}
+		return intValue(floor), nil
+	case "/":
+		r := new(big.Rat).Set(vs[0].rat())
+		for _, d := range vs[1:] {
+			if d.rat().Sign() == 0 {
+				return value{}, fmt.Errorf("/ by zero")
+			}
+			r.Quo(r, d.rat())
+		}
+		return realValue(r), nil
+	}
+
+	// +, - and *
+	if t.Id == "-" && len(vs) == 1 {
+		if isInt {
+			return intValue(new(big.Int).Neg(vs[0].n)), nil
+		}
+		return realValue(new(big.Rat).Neg(vs[0].r)), nil
+	}
+	if isInt {
+		n := new(big.Int).Set(vs[0].n)
+		for _, v := range vs[1:] {
+			switch t.Id {
+			case "+":
+				n.Add(n, v.n)
+			case "-":
+				n.Sub(n, v.n)
+			case "*":
+				n.Mul(n, v.n)
+			}
+		}
+		return intValue(n), nil
+	}
+	r := new(big.Rat).Set(vs[0].rat())
+	for _, v := range vs[1:] {
+		switch t.Id {
+		case "+":
+			r.Add(r, v.rat())
+		case "-":
+			r.Sub(r, v.rat())
+		case "*":
+			r.Mul(r, v.rat())
+		}
+	}
+	return realValue(r), nil
+}
+
+func bvNeg(a value) value {
+	return bvValue(new(big.Int).Neg(a.n), a.width)
+}
+
+func bvUDiv(a, b value) value {
+	if b.n.Sign() == 0 {
+		return bvValue(bvMask(a.width), a.width)
+	}
+	return bvValue(new(big.Int).Quo(a.n, b.n), a.width)
+}
+
+func bvURem(a, b value) value {
+	if b.n.Sign() == 0 {
+		return a
+	}
+	return bvValue(new(big.Int).Rem(a.n, b.n), a.width)
+}
+
+func (v value) msb() bool {
+	return v.n.Bit(int(v.width-1)) == 1
+}
+
+// bvSigned implements bvsdiv, bvsrem and bvsmod as SMT-LIB defines
+// them, in terms of the unsigned operations.
+func bvSigned(op Identifier, s, t value) value {
+	abs := func(v value) value {
+		if v.msb() {
+			return bvNeg(v)
+		}
+		return v
+	}
+	switch op {
+	case "bvsdiv":
+		q := bvUDiv(abs(s), abs(t))
+		if s.msb() != t.msb() {
+			return bvNeg(q)
+		}
+		return q
+	case "bvsrem":
+		r := bvURem(abs(s), abs(t))
+		if s.msb() {
+			return bvNeg(r)
+		}
+		return r
+	default: // bvsmod
+		u := bvURem(abs(s), abs(t))
+		switch {
+		case u.n.Sign() == 0 || !s.msb() && !t.msb():
+			return u
+		case s.msb() && !t.msb():
+			return bvValue(new(big.Int).Add(bvNeg(u).n, t.n), s.width)
+		case !s.msb() && t.msb():
+			return bvValue(new(big.Int).Add(u.n, t.n), s.width)
+		default:
+			return bvNeg(u)
+		}
+	}
+}
+
+func bvShift(op Identifier, a, b value) value {
+	w := a.width
+	if !b.n.IsInt64() || b.n.Int64() >= w {
+		if op == "bvashr" && a.msb() {
+			return bvValue(bvMask(w), w)
+		}
+		return bvValue(new(big.Int), w)
+	}
+	k := uint(b.n.Int64())
+	switch op {
+	case "bvshl":
+		return bvValue(new(big.Int).Lsh(a.n, k), w)
+	case "bvlshr":
+		return bvValue(new(big.Int).Rsh(a.n, k), w)
+	default: // bvashr
+		return bvValue(new(big.Int).Rsh(a.signed(), k), w)
+	}
+}
+
+func concat(a, b value) value {
+	n := new(big.Int).Lsh(a.n, uint(b.width))
+	return bvValue(n.Or(n, b.n), a.width+b.width)
+}
+
+func (e *evaluator) bv(t *App) (value, error) {
+	if t.Id == "bv2nat" || t.Id == "bv2int" {
+		vs, err := e.evalArgs(t.Id, t.Args, bvKind)
+		if err != nil {
+			return value{}, err
+		}
+		if len(vs) != 1 {
+			return value{}, fmt.Errorf("%s needs 1 argument", t.Id)
+		}
+		return intValue(vs[0].n), nil
+	}
+
+	vs, err := e.evalArgs(t.Id, t.Args, bvKind)
+	if err != nil {
+		return value{}, err
+	}
+	if len(vs) == 0 {
+		return value{}, fmt.Errorf("%s needs arguments", t.Id)
+	}
+	if t.Id == "concat" {
+		v := vs[0]
+		for _, w := range vs[1:] {
+			v = concat(v, w)
+		}
+		return v, nil
+	}
+	for _, v := range vs[1:] {
+		if v.width != vs[0].width {
+			return value{}, fmt.Errorf("%s: BitVecs of widths %d and %d", t.Id, vs[0].width, v.width)
+		}
+	}
+	a, w := vs[0], vs[0].width
+
+	switch t.Id {
+	case "bvnot":
+		return bvValue(new(big.Int).Not(a.n), w), nil
+	case "bvneg":
+		return bvNeg(a), nil
+	}
+	if len(vs) < 2 {
+		return value{}, fmt.Errorf("%s needs at least 2 arguments", t.Id)
+	}
+	b := vs[1]
+
+	switch t.Id {
+	case "bvand", "bvor", "bvxor", "bvadd", "bvmul":
+		// left associative
+		n := new(big.Int).Set(a.n)
+		for _, v := range vs[1:] {
+			switch t.Id {
+			case "bvand":
+				n.And(n, v.n)
+			case "bvor":
+				n.Or(n, v.n)
+			case "bvxor":
+				n.Xor(n, v.n)
+			case "bvadd":
+				n.Add(n, v.n)
+			case "bvmul":
+				n.Mul(n, v.n)
+			}
+		}
+		return bvValue(n, w), nil
+	}
+	if len(vs) != 2 {
+		return value{}, fmt.Errorf("%s needs 2 arguments", t.Id)
+	}
+
+	switch t.Id {
+	case "bvnand":
+		return bvValue(new(big.Int).Not(new(big.Int).And(a.n, b.n)), w), nil
+	case "bvnor":
+		return bvValue(new(big.Int).Not(new(big.Int).Or(a.n, b.n)), w), nil
+	case "bvxnor":
+		return bvValue(new(big.Int).Not(new(big.Int).Xor(a.n, b.n)), w), nil
+	case "bvsub":
+		return bvValue(new(big.Int).Sub(a.n, b.n), w), nil
+	case "bvudiv":
+		return bvUDiv(a, b), nil
+	case "bvurem":
+		return bvURem(a, b), nil
+	case "bvsdiv", "bvsrem", "bvsmod":
+		return bvSigned(t.Id, a, b), nil
+	case "bvshl", "bvlshr", "bvashr":
+		return bvShift(t.Id, a, b), nil
+	case "bvcomp":
+		if a.n.Cmp(b.n) == 0 {
+			return bvValue(big.NewInt(1), 1), nil
+		}
+		return bvValue(big.NewInt(0), 1), nil
+	case "bvult", "bvule", "bvugt", "bvuge":
+		return boolValue(compare(t.Id[2:], a.n.Cmp(b.n))), nil
+	case "bvslt", "bvsle", "bvsgt", "bvsge":
+		return boolValue(compare(t.Id[2:], a.signed().Cmp(b.signed()))), nil
+	}
+	return value{}, fmt.Errorf("can't evaluate %s", t.Id)
+}
+
+// compare interprets c, the result of a Cmp, for the comparison op:
+// ult, sle and so on.
+func compare(op Identifier, c int) bool {
+	switch op[1:] {
+	case "lt":
+		return c < 0
+	case "le":
+		return c <= 0
+	case "gt":
+		return c > 0
+	default: // ge
+		return c >= 0
+	}
+}
+
+func (e *evaluator) indexedApp(t *IndexedApp) (value, error) {
+	index := func(n int) ([]int64, error) {
+		if len(t.Indices) != n || len(t.Args) != 1 {
+			return nil, fmt.Errorf("(_ %s) needs %d indices and 1 argument", t.Id, n)
+		}
+		return t.Indices, nil
+	}
+
+	if t.Id == "divisible" {
+		idx, err := index(1)
+		if err != nil {
+			return value{}, err
+		}
+		vs, err := e.evalArgs(t.Id, t.Args, intKind)
+		if err != nil {
+			return value{}, err
+		}
+		if idx[0] <= 0 {
+			return value{}, fmt.Errorf("divisible by %d", idx[0])
+		}
+		m := new(big.Int).Mod(vs[0].n, big.NewInt(idx[0]))
+		return boolValue(m.Sign() == 0), nil
+	}
+	if t.Id == "int2bv" {
+		idx, err := index(1)
+		if err != nil {
+			return value{}, err
+		}
+		vs, err := e.evalArgs(t.Id, t.Args, intKind)
+		if err != nil {
+			return value{}, err
+		}
+		return bvValue(vs[0].n, idx[0]), nil
+	}
+	if strings.HasPrefix(string(t.Id), "bv") && len(t.Indices) == 1 && len(t.Args) == 0 {
+		// a literal, (_ bvN W), like those wider than a BitVec
+		if n, ok := new(big.Int).SetString(string(t.Id[2:]), 10); ok && n.Sign() >= 0 {
+			return bvValue(n, t.Indices[0]), nil
+		}
+	}
+
+	var idx []int64
+	var err error
+	switch t.Id {
+	case "extract":
+		idx, err = index(2)
+	case "zero_extend", "sign_extend", "repeat", "rotate_left", "rotate_right":
+		idx, err = index(1)
+	default:
+		return value{}, fmt.Errorf("can't evaluate (_ %s)", t.Id)
+	}
+	if err != nil {
+		return value{}, err
+	}
+	vs, err := e.evalArgs(t.Id, t.Args, bvKind)
+	if err != nil {
+		return value{}, err
+	}
+	a, w := vs[0], vs[0].width
+
+	switch t.Id {
+	case "extract":
+		i, j := idx[0], idx[1]
+		if j < 0 || j > i || i >= w {
+			return value{}, fmt.Errorf("bad extract (_ extract %d %d) of %d bits", i, j, w)
+		}
+		return bvValue(new(big.Int).Rsh(a.n, uint(j)), i-j+1), nil
+	case "zero_extend":
+		return bvValue(a.n, w+idx[0]), nil
+	case "sign_extend":
+		return bvValue(a.signed(), w+idx[0]), nil
+	case "repeat":
+		if idx[0] < 1 {
+			return value{}, fmt.Errorf("bad repeat count %d", idx[0])
+		}
+		v := a
+		for i := int64(1); i < idx[0]; i++ {
+			v = concat(v, a)
+		}
+		return v, nil
+	}
+
+	// rotations
+	k := idx[0] % w
+	if t.Id == "rotate_right" {
+		k = (w - k) % w
+	}
+	n := new(big.Int).Lsh(a.n, uint(k))
+	n.Or(n, new(big.Int).Rsh(a.n, uint(w-k)))
+	return bvValue(n, w), nil
+}
@@ -7,25 +7,17 @@ package smt
 import (
 	"bufio"
 	"bytes"
-	"fmt"
-	"go/token"
 	"io"
-	"log"
-	"strings"
-	"unicode"
-	"unicode/utf8"
 )
 
-//go:generate go tool yacc -o parse.go -p smt parse.y
-
-const eof = 0
-
 type iType int
 
 const (
 	iEOF iType = iota
 	iInt
+	iDecimal
 	iHex
+	iBinary
 	iSymbol
 	iString
 	iKeyword
@@ -34,284 +26,277 @@ const (
 )
 
 type tok struct {
-	pos    int
-	val    string
-	ival   int64
-	kind   iType
-	yyKind int
+	pos  Position
+	val  string
+	kind iType
 }
 
-type stateFn func(*smtLex) stateFn
+// maxSourceLine is the longest line we keep around to quote in
+// ParseErrors.
+const maxSourceLine = 4096
 
+// smtLex splits its input into SMT-LIB tokens.  Besides the
+// standard ';' comments, it skips C-style '//' and '/* */' comments.
 type smtLex struct {
-	in    *bufio.Scanner
-	line  string
-	pos   int // current position in the input
-	start int // start of this token
-	width int // width of the last rune
-	last  tok
-	items chan tok // channel of scanned items
-	state stateFn
-
-	parser *Parser
-}
-
-func (l *smtLex) Lex(lval *smtSymType) int {
-	for {
-		select {
-		case item := <-l.items:
-			lval.tok = item
-			return item.yyKind
-		default:
-			l.state = l.state(l)
-		}
-	}
+	in   *bufio.Reader
+	pos  Position // position of the next byte
+	err  error    // sticky error from in, other than io.EOF
+	line []byte   // the current line, up to pos
+	buf  []byte   // scratch space for the current token
 }
 
-func newSmtLex(r io.Reader, p *Parser) *smtLex {
+func newSmtLex(r io.Reader) *smtLex {
 	return &smtLex{
-		in:     bufio.NewScanner(r),
-		items:  make(chan tok, 2),
-		state:  lexStatement,
-		parser: p,
+		in:  bufio.NewReader(r),
+		pos: Position{Line: 1, Column: 1},
 	}
 }
 
-func (l *smtLex) getLine(pos token.Position) string {
-	col := pos.Column
-	if col > pos.Offset {
-		col = pos.Offset
+// next returns the next byte of input, or false at the end of the
+// input.
+func (l *smtLex) next() (byte, bool) {
+	if l.err != nil {
+		return 0, false
 	}
-	p := pos.Offset - col
-	if p >= len(l.line) {
-		return fmt.Sprintf("getLine: o%d c%d, len%d",
-			pos.Offset, col, len(l.line))
+	c, err := l.in.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		return 0, false
 	}
-	result := l.line[pos.Offset-col:]
-	if newline := strings.IndexRune(result, '\n'); newline != -1 {
-		result = result[:newline]
+	l.pos.Offset++
+	if c == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+		l.line = l.line[:0]
+	} else {
+		l.pos.Column++
+		if len(l.line) < maxSourceLine {
+			l.line = append(l.line, c)
+		}
 	}
-	return result
+	return c, true
 }
 
-func (l *smtLex) Error(s string) {
-	line := l.line
-
-	col := l.pos
-	if col > len(line) {
-		col = len(line)
+func (l *smtLex) peek() (byte, bool) {
+	if l.err != nil {
+		return 0, false
 	}
-	// we want the number of spaces (taking into account tabs)
-	// before the problematic token
-	prefixLen := col + strings.Count(line[:col], "\t")*7 - 1
-	prefix := strings.Repeat(" ", prefixLen)
-
-	line = strings.Replace(line, "\t", "        ", -1)
-
-	fmt.Printf("%d: error: %s\n", col, s)
-	fmt.Printf("%s\n", line)
-	fmt.Printf("%s^\n", prefix)
-}
-
-func (l *smtLex) next() rune {
-	if l.pos >= len(l.line) {
-		ok := l.in.Scan()
-		if !ok {
-			l.width = 0
-			return 0
+	b, err := l.in.Peek(1)
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
 		}
-		l.line = l.in.Text() + "\n"
-		l.pos = 0
-		l.start = 0
+		return 0, false
 	}
-	r, width := utf8.DecodeRuneInString(l.line[l.pos:])
-	l.pos += width
-	l.width = width
-
-	return r
-}
-
-func (l *smtLex) backup() {
-	l.pos -= l.width
+	return b[0], true
 }
 
-func (l *smtLex) peek() rune {
-	peek := l.next()
-	l.backup()
-	return peek
-}
-
-func (l *smtLex) ignore() {
-	if l.start == l.pos {
-		log.Printf("ignore: start == pos (%d), error.", l.start)
+// source returns the line of input containing the current position,
+// including whatever is already buffered past it, without blocking
+// for more input.
+func (l *smtLex) source() string {
+	if len(l.line) >= maxSourceLine {
+		return ""
 	}
-	l.start = l.pos
+	rest, _ := l.in.Peek(l.in.Buffered())
+	if i := bytes.IndexAny(rest, "\r\n"); i >= 0 {
+		rest = rest[:i]
+	}
+	return string(l.line) + string(rest)
 }
 
-func (l *smtLex) accept(valid string) bool {
-	if strings.IndexRune(valid, l.next()) >= 0 {
-		return true
+func (l *smtLex) errorf(t tok, msg string) *ParseError {
+	source := ""
+	if t.pos.Line == l.pos.Line {
+		source = l.source()
+	}
+	return &ParseError{
+		Position: t.pos,
+		Token:    t.val,
+		Message:  msg,
+		Source:   source,
 	}
-	l.backup()
-	return false
 }
 
-func (l *smtLex) acceptRun(valid string) {
-	for strings.IndexRune(valid, l.next()) >= 0 {
-	}
-	l.backup()
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
 }
 
-func (l *smtLex) emit(yyTy rune, ty iType) {
-	t := tok{
-		pos:    l.pos,
-		val:    l.line[l.start:l.pos],
-		yyKind: int(yyTy),
-		kind:   ty,
-	}
-	//log.Printf("t(%s): %#v\n", l.line[l.start:l.pos], t)
-	l.last = t
-	l.items <- t
-	if ty != iEOF {
-		l.ignore()
-	}
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
 }
 
-func (l *smtLex) errorf(format string, args ...interface{}) stateFn {
-	log.Printf(format, args...)
-	l.emit(eof, iEOF)
-	return nil
+// isDelimiter reports whether c ends a symbol, numeral or keyword.
+func isDelimiter(c byte) bool {
+	return isSpace(c) || c == '(' || c == ')' || c == '"' || c == ';' || c == '|'
 }
 
-func lexStatement(l *smtLex) stateFn {
-	switch r := l.next(); {
-	case r == eof:
-		l.emit(eof, iEOF)
-	case r == '/':
-		if l.peek() == '/' {
-			l.next()
-			return lexComment
+// skip consumes whitespace and comments.
+func (l *smtLex) skip() {
+	for {
+		c, ok := l.peek()
+		if !ok {
+			return
 		}
-		if l.peek() == '*' {
+		switch {
+		case isSpace(c):
 			l.next()
-			return lexMultiComment
+		case c == ';':
+			l.skipLine()
+		case c == '/':
+			b, _ := l.in.Peek(2)
+			if len(b) < 2 {
+				return
+			}
+			switch b[1] {
+			case '/':
+				l.skipLine()
+			case '*':
+				l.next()
+				l.next()
+				l.skipMultiComment()
+			default:
+				return
+			}
+		default:
+			return
 		}
-		return lexSymbol
-	case unicode.IsSpace(r):
-		//	log.Print("1 ignoring:", l.s[l.start:l.pos])
-		l.ignore()
-	case unicode.IsDigit(r):
-		l.backup()
-		return lexInteger
-	case isOperator(r):
-		l.backup()
-		return lexOperator
-	case isKeywordStart(r):
-		return lexKeyword
-	case r == '"':
-		l.backup()
-		return lexString
-	default:
-		return lexSymbol
 	}
-
-	return lexStatement
-}
-
-func lexOperator(l *smtLex) stateFn {
-	var ty iType
-	r := l.next()
-	switch {
-	case r == '(':
-		ty = iLParen
-	case r == ')':
-		ty = iRParen
-	default:
-		panic("unknown operator type")
-	}
-	l.emit(r, ty)
-	return lexStatement
 }
 
-func lexComment(l *smtLex) stateFn {
-	// skip everything until the end of the line, or the end of
-	// the file, whichever is first
-	for r := l.next(); r != '\n' && r != eof; r = l.next() {
+func (l *smtLex) skipLine() {
+	for c, ok := l.next(); ok && c != '\n'; c, ok = l.next() {
 	}
-	l.backup()
-	//	log.Print("2 ignoring:", l.s[l.start:l.pos])
-	l.ignore()
-	return lexStatement
 }
 
-func lexMultiComment(l *smtLex) stateFn {
-	// skip everything until the end of the line, or the end of
-	// the file, whichever is first
-	for r := l.next(); ; r = l.next() {
-		if r == eof {
-			l.backup()
-			break
-		}
-		if r != '*' {
+func (l *smtLex) skipMultiComment() {
+	for c, ok := l.next(); ok; c, ok = l.next() {
+		if c != '*' {
 			continue
 		}
-		if l.peek() == '/' {
+		if c, ok := l.peek(); ok && c == '/' {
 			l.next()
-			break
+			return
 		}
 	}
-	//	log.Print("2 ignoring:", l.s[l.start:l.pos])
-	l.ignore()
-	return lexStatement
 }
 
-func lexInteger(l *smtLex) stateFn {
-	l.acceptRun("0123456789")
-	l.emit(yINT, iInt)
-	return lexStatement
-}
+// lex returns the next token.  A token with a non-nil error is
+// still returned, so that the caller can report where it was.
+func (l *smtLex) lex() (tok, *ParseError) {
+	l.skip()
 
-func lexString(l *smtLex) stateFn {
-	delim := l.next()
-	l.ignore()
-	for r := l.next(); r != delim && r != eof; r = l.next() {
+	t := tok{pos: l.pos}
+	c, ok := l.next()
+	if !ok {
+		t.kind = iEOF
+		return t, nil
 	}
-	l.backup()
 
-	if l.peek() != delim {
-		return l.errorf("unexpected EOF")
+	switch {
+	case c == '(':
+		t.kind, t.val = iLParen, "("
+	case c == ')':
+		t.kind, t.val = iRParen, ")"
+	case c == '"':
+		return l.lexString(t)
+	case c == '|':
+		return l.lexQuotedSymbol(t)
+	case c == ':':
+		t.kind = iKeyword
+		t.val = l.lexRun(nil)
+	case c == '#':
+		return l.lexBitVec(t)
+	case isDigit(c):
+		t.kind = iInt
+		t.val = l.lexRun([]byte{c})
+		dots := 0
+		for i := 0; i < len(t.val); i++ {
+			if t.val[i] == '.' {
+				t.kind = iDecimal
+				dots++
+			} else if !isDigit(t.val[i]) {
+				return t, l.errorf(t, "malformed number")
+			}
+		}
+		if dots > 1 || t.val[len(t.val)-1] == '.' {
+			return t, l.errorf(t, "malformed number")
+		}
+	default:
+		t.kind = iSymbol
+		t.val = l.lexRun([]byte{c})
 	}
-	l.emit(ySTRING, iString)
-	l.next()
-	l.ignore()
-	return lexStatement
+	return t, nil
 }
 
-func lexKeyword(l *smtLex) stateFn {
-	l.ignore()
-	for r := l.next(); r != eof && !isOperator(r) && !unicode.IsSpace(r); r = l.next() {
+// lexRun returns prefix followed by every byte up to the next
+// delimiter.
+func (l *smtLex) lexRun(prefix []byte) string {
+	l.buf = append(l.buf[:0], prefix...)
+	for c, ok := l.peek(); ok && !isDelimiter(c); c, ok = l.peek() {
+		l.next()
+		l.buf = append(l.buf, c)
 	}
-	l.backup()
-	l.emit(yKEYWORD, iKeyword)
-	return lexStatement
+	return string(l.buf)
 }
 
-func lexSymbol(l *smtLex) stateFn {
-	for r := l.next(); r != eof && !isOperator(r) && !unicode.IsSpace(r); r = l.next() {
+func (l *smtLex) lexString(t tok) (tok, *ParseError) {
+	t.kind = iString
+	l.buf = l.buf[:0]
+	for {
+		c, ok := l.next()
+		if !ok {
+			t.val = `"` + string(l.buf)
+			return t, l.errorf(t, "unterminated string")
+		}
+		if c == '"' {
+			// "" is an escaped quote
+			if c, ok := l.peek(); !ok || c != '"' {
+				break
+			}
+			l.next()
+		}
+		l.buf = append(l.buf, c)
 	}
-	l.backup()
-	l.emit(ySYMBOL, iSymbol)
-	return lexStatement
-}
-
-func isStringStart(r rune) bool {
-	return r == '"'
+	t.val = string(l.buf)
+	return t, nil
 }
 
-func isKeywordStart(r rune) bool {
-	return r == ':'
+func (l *smtLex) lexQuotedSymbol(t tok) (tok, *ParseError) {
+	t.kind = iSymbol
+	l.buf = l.buf[:0]
+	for {
+		c, ok := l.next()
+		if !ok {
+			t.val = "|" + string(l.buf)
+			return t, l.errorf(t, "unterminated quoted symbol")
+		}
+		if c == '|' {
+			break
+		}
+		if c == '\\' {
+			return t, l.errorf(t, "backslash in quoted symbol")
+		}
+		l.buf = append(l.buf, c)
+	}
+	t.val = string(l.buf)
+	return t, nil
 }
 
-func isOperator(r rune) bool {
-	return bytes.IndexRune([]byte("()"), r) > -1
+func (l *smtLex) lexBitVec(t tok) (tok, *ParseError) {
+	t.val = l.lexRun([]byte{'#'})
+	if len(t.val) < 3 {
+		return t, l.errorf(t, "malformed bit-vector literal")
+	}
+	switch t.val[1] {
+	case 'x':
+		t.kind = iHex
+	case 'b':
+		t.kind = iBinary
+	default:
+		return t, l.errorf(t, "malformed bit-vector literal")
+	}
+	return t, nil
 }
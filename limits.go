@@ -0,0 +1,22 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"time"
+)
+
+// Limits bounds the resources a single CheckSatLimited may use.  A
+// zero field is unlimited.
+type Limits struct {
+	// Timeout is the wall-clock time allowed.
+	Timeout time.Duration
+	// ResourceLimit is a solver-specific count of work (z3's
+	// rlimit, cvc5's rlimit-per), which unlike Timeout gives the
+	// same result on every run.
+	ResourceLimit int64
+	// MemoryMB is the memory allowed, in megabytes.
+	MemoryMB int64
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Option names a solver option, without its leading colon.
+type Option string
+
+// The standard SMT-LIB options.
+const (
+	OptionDiagnosticOutputChannel   Option = "diagnostic-output-channel"
+	OptionGlobalDeclarations        Option = "global-declarations"
+	OptionInteractiveMode           Option = "interactive-mode"
+	OptionPrintSuccess              Option = "print-success"
+	OptionProduceAssertions         Option = "produce-assertions"
+	OptionProduceAssignments        Option = "produce-assignments"
+	OptionProduceModels             Option = "produce-models"
+	OptionProduceProofs             Option = "produce-proofs"
+	OptionProduceUnsatAssumptions   Option = "produce-unsat-assumptions"
+	OptionProduceUnsatCores         Option = "produce-unsat-cores"
+	OptionRandomSeed                Option = "random-seed"
+	OptionRegularOutputChannel      Option = "regular-output-channel"
+	OptionReproducibleResourceLimit Option = "reproducible-resource-limit"
+	OptionVerbosity                 Option = "verbosity"
+)
+
+// Info names a piece of information about a solver, or about the
+// script being run, without its leading colon.
+type Info string
+
+// The standard SMT-LIB info flags.  Name through ReasonUnknown can
+// be got with GetInfo; SMTLIBVersion through Status are set with
+// SetInfo to describe a script.
+const (
+	InfoAllStatistics        Info = "all-statistics"
+	InfoAssertionStackLevels Info = "assertion-stack-levels"
+	InfoAuthors              Info = "authors"
+	InfoErrorBehavior        Info = "error-behavior"
+	InfoName                 Info = "name"
+	InfoReasonUnknown        Info = "reason-unknown"
+	InfoVersion              Info = "version"
+
+	InfoSMTLIBVersion Info = "smt-lib-version"
+	InfoSource        Info = "source"
+	InfoCategory      Info = "category"
+	InfoLicense       Info = "license"
+	InfoStatus        Info = "status"
+)
+
+// OptionValue is the value of an option or info flag: a BoolValue,
+// IntValue, DecimalValue, StringValue, SymbolValue or KeywordValue.
+type OptionValue interface {
+	optionValue()
+}
+
+type BoolValue bool
+type IntValue int64
+
+// DecimalValue is a decimal number, like 2.6, kept as written.
+type DecimalValue string
+type StringValue string
+type SymbolValue string
+
+// KeywordValue is a keyword, without its leading colon.
+type KeywordValue string
+
+func (BoolValue) optionValue()    {}
+func (IntValue) optionValue()     {}
+func (DecimalValue) optionValue() {}
+func (StringValue) optionValue()  {}
+func (SymbolValue) optionValue()  {}
+func (KeywordValue) optionValue() {}
+
+func OptionValueToSexp(v OptionValue) Sexp {
+	switch v := v.(type) {
+	case BoolValue:
+		if v {
+			return &SSymbol{"true"}
+		}
+		return &SSymbol{"false"}
+	case IntValue:
+		if v < 0 {
+			return negative(int64(v))
+		}
+		return &SInt{int64(v)}
+	case DecimalValue:
+		return &SDecimal{string(v)}
+	case StringValue:
+		return &SString{string(v)}
+	case SymbolValue:
+		return &SSymbol{string(v)}
+	case KeywordValue:
+		return &SKeyword{string(v)}
+	}
+	panic(fmt.Sprintf("unknown OptionValue %T", v))
+}
+
+func SexpToOptionValue(sexp Sexp) (OptionValue, error) {
+	switch s := sexp.(type) {
+	case *SSymbol:
+		switch s.Symbol {
+		case "true":
+			return BoolValue(true), nil
+		case "false":
+			return BoolValue(false), nil
+		}
+		return SymbolValue(s.Symbol), nil
+	case *SInt:
+		return IntValue(s.Int), nil
+	case *SDecimal:
+		return DecimalValue(s.Decimal), nil
+	case *SString:
+		return StringValue(s.Str), nil
+	case *SKeyword:
+		return KeywordValue(s.Keyword), nil
+	case *SList:
+		if len(s.List) == 2 && IsSymbol(s.List[0], "-") {
+			if n, ok := s.List[1].(*SInt); ok {
+				return IntValue(-n.Int), nil
+			}
+			// only math.MinInt64's magnitude is too big for an SInt
+			if n, ok := s.List[1].(*SBigInt); ok {
+				if m := new(big.Int).Neg(n.Int); m.IsInt64() {
+					return IntValue(m.Int64()), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("not an option value: %s", sexp)
+}
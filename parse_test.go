@@ -1,8 +1,13 @@
 package smt
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -22,6 +27,14 @@ var sexpRTData = []sexpRTTest{
 	{"symbol", &SSymbol{"symbol"}},
 	{`"string"`, &SString{"string"}},
 	{`"!string!"`, &SString{"!string!"}},
+	{`"say ""hi"""`, &SString{`say "hi"`}},
+	{"|a symbol|", &SSymbol{"a symbol"}},
+	{"1.50", &SDecimal{"1.50"}},
+	{"#x0f", &SBitVec{15, 8}},
+	{"#b101", &SBitVec{5, 3}},
+	{"#xffffffffffffffff", &SBitVec{-1, 64}},
+	{"(; comment\n a // another\n /* and\n another */ b)", &SList{[]Sexp{&SSymbol{"a"}, &SSymbol{"b"}}}},
+	{"(/ 1 3)", &SList{[]Sexp{&SSymbol{"/"}, &SInt{1}, &SInt{3}}}},
 }
 
 func TestSexpRT(t *testing.T) {
@@ -51,3 +64,210 @@ func TestSexpRT(t *testing.T) {
 		}
 	}
 }
+
+func TestBitVec64RT(t *testing.T) {
+	sexp, err := NewParser(strings.NewReader("#x8000000000000001")).Read()
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	printed := sexp.String()
+	if printed != "(_ bv9223372036854775809 64)" {
+		t.Fatalf("printed as %s", printed)
+	}
+	term := parseTerm(t, printed)
+	if !reflect.DeepEqual(term, &BitVec{-0x7fffffffffffffff, 64}) {
+		t.Fatalf("SexpToTerm(%s) = %#v", printed, term)
+	}
+	if s := TermToSexp(term).String(); s != printed {
+		t.Fatalf("round trip: %s != %s", s, printed)
+	}
+	v, err := Eval(NewApp("bvadd", term, term), nil)
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	if s := TermToSexp(v).String(); s != "(_ bv2 64)" {
+		t.Fatalf("Eval: %s", s)
+	}
+}
+
+func TestBigLiterals(t *testing.T) {
+	// as in a model with 128-bit values
+	const model = `((define-fun x () (_ BitVec 128) #x0000000000000001ffffffffffffffff)
+ (define-fun n () Int (- 36893488147419103231)))`
+	sexp, err := NewParser(strings.NewReader(model)).Read()
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	defs := sexp.(*SList).List
+	x := defs[0].(*SList).List[4]
+	if s := x.String(); s != "(_ bv36893488147419103231 128)" {
+		t.Fatalf("printed as %s", s)
+	}
+	n := defs[1].(*SList).List[4]
+	if s := n.String(); s != "(- 36893488147419103231)" {
+		t.Fatalf("printed as %s", s)
+	}
+
+	xt, err := SexpToTerm(x)
+	if err != nil {
+		t.Fatalf("SexpToTerm(%s): %s", x, err)
+	}
+	nt, err := SexpToTerm(n)
+	if err != nil {
+		t.Fatalf("SexpToTerm(%s): %s", n, err)
+	}
+	if s := TermToSexp(nt).String(); s != n.String() {
+		t.Fatalf("round trip: %s != %s", s, n)
+	}
+
+	v, err := Eval(NewApp("bvadd", xt, xt), nil)
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	if s := TermToSexp(v).String(); s != "(_ bv73786976294838206462 128)" {
+		t.Fatalf("Eval: %s", s)
+	}
+	v, err = Eval(NewApp("+", nt, parseTerm(t, "36893488147419103232")), nil)
+	if err != nil {
+		t.Fatalf("Eval: %s", err)
+	}
+	if s := TermToSexp(v).String(); s != "1" {
+		t.Fatalf("Eval: %s", s)
+	}
+}
+
+func TestParserPositions(t *testing.T) {
+	input := "(set-logic QF_LIA)\n  (assert\n\t(= a 3))\nsat"
+	p := NewParser(strings.NewReader(input))
+
+	before := runtime.NumGoroutine()
+
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("Next: %s", err)
+	}
+	sexp, err := p.Next()
+	if err != nil {
+		t.Fatalf("Next: %s", err)
+	}
+	eq := sexp.(*SList).List[1].(*SList)
+	expected := []struct {
+		sexp Sexp
+		pos  Position
+	}{
+		{sexp, Position{Offset: 21, Line: 2, Column: 3}},
+		{eq, Position{Offset: 30, Line: 3, Column: 2}},
+		{eq.List[2], Position{Offset: 35, Line: 3, Column: 7}},
+	}
+	for _, e := range expected {
+		pos, ok := p.Pos(e.sexp)
+		if !ok || pos != e.pos {
+			t.Fatalf("Pos(%s): %v (%v) != %v", e.sexp, pos, ok, e.pos)
+		}
+	}
+
+	sexp, err = p.Next()
+	if err != nil || !IsSymbol(sexp, "sat") {
+		t.Fatalf("Next: %v %v", sexp, err)
+	}
+	if pos, _ := p.Pos(sexp); pos.Line != 4 || pos.Column != 1 {
+		t.Fatalf("Pos(sat): %v", pos)
+	}
+	if _, err = p.Next(); err != ParserEOF {
+		t.Fatalf("expected EOF, not %v", err)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("parser started goroutines: %d > %d", after, before)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	p := NewParser(strings.NewReader("(a b)\n\t(c))"))
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("Next: %s", err)
+	}
+	if _, err := p.Next(); err != nil {
+		t.Fatalf("Next: %s", err)
+	}
+	_, err := p.Next()
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, not %#v", err)
+	}
+	if perr.Line != 2 || perr.Column != 5 || perr.Offset != 10 || perr.Token != ")" || perr.Source != "\t(c))" {
+		t.Fatalf("unexpected error: %#v", perr)
+	}
+	if expected := "        (c))\n           ^"; perr.Excerpt() != expected {
+		t.Fatalf("Excerpt:\n%s\n!=\n%s", perr.Excerpt(), expected)
+	}
+	if _, err = p.Next(); err != ParserEOF {
+		t.Fatalf("expected EOF, not %v", err)
+	}
+
+	// after an error, parsing picks up with the next top-level sexp
+	p = NewParser(strings.NewReader("(a (b #q1) c) (ok)"))
+	if _, err = p.Next(); err == nil {
+		t.Fatalf("expected error")
+	}
+	if sexp, err := p.Next(); err != nil || sexp.String() != "(ok)" {
+		t.Fatalf("Next after error: %v %v", sexp, err)
+	}
+
+	_, err = NewParser(strings.NewReader(`(echo "abc`)).Next()
+	if perr, ok = err.(*ParseError); !ok || perr.Message != "unterminated string" || perr.Column != 7 || perr.Token != `"abc` {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+// benchScript returns a large, QF_BV-style SMT-LIB script.
+func benchScript() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("(set-logic QF_BV)\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&buf, "(declare-const x%d (_ BitVec 32))\n", i)
+	}
+	for i := 1; i < 1000; i++ {
+		fmt.Fprintf(&buf, "; step %d\n(assert (= x%d (bvadd (bvmul x%d #x0000000%x) (_ bv%d 32) |quoted sym|)))\n", i, i, i-1, i%16, i)
+	}
+	buf.WriteString("(check-sat)\n(get-model)\n")
+	return buf.Bytes()
+}
+
+func BenchmarkParse(b *testing.B) {
+	script := benchScript()
+	b.SetBytes(int64(len(script)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := NewParser(bytes.NewReader(script))
+		for {
+			_, err := p.Next()
+			if err == ParserEOF {
+				break
+			} else if err != nil {
+				b.Fatalf("Next: %s", err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	var sexps []Sexp
+	p := NewParser(bytes.NewReader(benchScript()))
+	for {
+		sexp, err := p.Next()
+		if err != nil {
+			break
+		}
+		sexps = append(sexps, sexp)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := bufio.NewWriter(ioutil.Discard)
+		for _, sexp := range sexps {
+			sexp.WriteTo(w)
+		}
+		w.Flush()
+	}
+}
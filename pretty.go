@@ -0,0 +1,172 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// PrettyPrinter formats s-expressions across multiple lines for
+// people to read.  Lists that fit in the remaining width are kept on
+// one line; longer lists put each argument on its own line, indented
+// under the list's head.  The bindings of a let are aligned with
+// each other.  The zero PrettyPrinter uses a width of 80 and an
+// indent of 2.
+type PrettyPrinter struct {
+	Width  int // maximum line width, a goal rather than a limit
+	Indent int // spaces of indentation per nesting level
+}
+
+// Fprint writes s followed by a newline to w.
+func (pp PrettyPrinter) Fprint(w io.Writer, s Sexp) error {
+	bw, ok := w.(bufferedWriter)
+	var flush *bufio.Writer
+	if !ok {
+		flush = bufio.NewWriter(w)
+		bw = flush
+	}
+
+	p := &pretty{printer: printer{w: bw}, width: pp.Width, indent: pp.Indent}
+	if p.width <= 0 {
+		p.width = 80
+	}
+	if p.indent <= 0 {
+		p.indent = 2
+	}
+	p.pp(s)
+	p.writeByte('\n')
+
+	if flush != nil {
+		return flush.Flush()
+	}
+	return p.err
+}
+
+// FprintTerm writes t, as converted by TermToSexp, followed by a
+// newline to w.
+func (pp PrettyPrinter) FprintTerm(w io.Writer, t Term) error {
+	return pp.Fprint(w, TermToSexp(t))
+}
+
+// Sprint returns the pretty-printed s, without a trailing newline.
+func (pp PrettyPrinter) Sprint(s Sexp) string {
+	var b strings.Builder
+	pp.Fprint(&b, s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+type pretty struct {
+	printer
+	width  int
+	indent int
+	col    int
+}
+
+// flat writes s on a single line.
+func (p *pretty) flat(s Sexp) {
+	before := p.n
+	p.sexp(s)
+	p.col += int(p.n - before)
+}
+
+func (p *pretty) writeText(s string) {
+	p.writeString(s)
+	p.col += len(s)
+}
+
+func (p *pretty) newline(col int) {
+	p.writeByte('\n')
+	p.writeString(strings.Repeat(" ", col))
+	p.col = col
+}
+
+// fits reports whether s can be printed on one line in room
+// columns, without looking at more of s than necessary.
+func fits(s Sexp, room int) bool {
+	return fitRoom(s, room) >= 0
+}
+
+func fitRoom(sexp Sexp, room int) int {
+	if room < 0 {
+		return room
+	}
+	s, ok := sexp.(*SList)
+	if !ok {
+		return room - len(sexp.String())
+	}
+	// parentheses, and spaces between elements
+	room -= 2
+	if len(s.List) > 1 {
+		room -= len(s.List) - 1
+	}
+	for _, child := range s.List {
+		if room = fitRoom(child, room); room < 0 {
+			break
+		}
+	}
+	return room
+}
+
+func (p *pretty) pp(sexp Sexp) {
+	s, ok := sexp.(*SList)
+	if !ok || len(s.List) == 0 || fits(s, p.width-p.col) {
+		p.flat(sexp)
+		return
+	}
+
+	if len(s.List) == 3 && IsSymbol(s.List[0], "let") {
+		if bindings, ok := s.List[1].(*SList); ok {
+			p.let(bindings, s.List[2])
+			return
+		}
+	}
+
+	start := p.col
+	p.writeText("(")
+	if _, ok := s.List[0].(*SList); ok {
+		// a list of lists, like a let's bindings or a model;
+		// line the elements up with each other.
+		p.pp(s.List[0])
+		for _, child := range s.List[1:] {
+			p.newline(start + 1)
+			p.pp(child)
+		}
+	} else {
+		p.flat(s.List[0])
+		for _, child := range s.List[1:] {
+			p.newline(start + p.indent)
+			p.pp(child)
+		}
+	}
+	p.writeText(")")
+}
+
+func (p *pretty) let(bindings *SList, body Sexp) {
+	start := p.col
+	p.writeText("(let (")
+	col := p.col
+	for i, b := range bindings.List {
+		if i > 0 {
+			p.newline(col)
+		}
+		binding, ok := b.(*SList)
+		if !ok || len(binding.List) != 2 || fits(binding, p.width-p.col) {
+			p.pp(b)
+			continue
+		}
+		// keep the name and the start of its value together
+		p.writeText("(")
+		p.flat(binding.List[0])
+		p.writeText(" ")
+		p.pp(binding.List[1])
+		p.writeText(")")
+	}
+	p.writeText(")")
+	p.newline(start + p.indent)
+	p.pp(body)
+	p.writeText(")")
+}
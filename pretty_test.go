@@ -0,0 +1,52 @@
+package smt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrettyPrinter(t *testing.T) {
+	x := NewConst("x")
+	a := Add(x, NewInt(1), NewConst("longer-name"))
+	b := Mul(a, a)
+	term := And(LT(b, NewInt(10)), GT(b, a), Equals(NewConst("y"), x))
+
+	expected := `(let ((_let_1 (+ x 1 longer-name)))
+  (let ((_let_2 (* _let_1 _let_1)))
+    (and
+      (< _let_2 10)
+      (> _let_2 _let_1)
+      (= y x))))`
+	pp := PrettyPrinter{Width: 40}
+	if s := pp.Sprint(TermToSexpShared(term)); s != expected {
+		t.Fatalf("Sprint:\n%s\n!=\n%s", s, expected)
+	}
+
+	expected = `(let ((_let_1 (+ x 1 longer-name))
+      (_let_2 (* y y)))
+  (and _let_1 _let_2))`
+	sexp, err := NewParser(strings.NewReader(expected)).Read()
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	if s := pp.Sprint(sexp); s != expected {
+		t.Fatalf("Sprint:\n%s\n!=\n%s", s, expected)
+	}
+
+	short := TermToSexp(Equals(x, NewInt(1)))
+	if s := pp.Sprint(short); s != short.String() {
+		t.Fatalf("short list not kept inline: %s", s)
+	}
+
+	// empty lists take 2 columns
+	def, err := NewParser(strings.NewReader("(define-fun x () Int 1)")).Read()
+	if err != nil {
+		t.Fatalf("Parse: %s", err)
+	}
+	narrow := PrettyPrinter{Width: len(def.String()) - 1}
+	for _, line := range strings.Split(narrow.Sprint(def), "\n") {
+		if len(line) > narrow.Width {
+			t.Fatalf("line wider than %d: %q", narrow.Width, line)
+		}
+	}
+}
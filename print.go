@@ -0,0 +1,157 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// bufferedWriter is implemented by *bufio.Writer, *bytes.Buffer and
+// *strings.Builder, which we can write to piecemeal without an
+// intermediate buffer.
+type bufferedWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+type printer struct {
+	w   bufferedWriter
+	n   int64
+	err error
+	buf [24]byte // scratch space for formatting integers
+}
+
+func sexpString(s Sexp) string {
+	var b strings.Builder
+	s.WriteTo(&b)
+	return b.String()
+}
+
+func writeSexp(w io.Writer, s Sexp) (int64, error) {
+	if bw, ok := w.(bufferedWriter); ok {
+		p := &printer{w: bw}
+		p.sexp(s)
+		return p.n, p.err
+	}
+
+	// what matters to the caller is how much made it to w, not
+	// into our buffer.
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	p := &printer{w: bw}
+	p.sexp(s)
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+func (p *printer) writeString(s string) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.WriteString(s)
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *printer) writeByte(c byte) {
+	if p.err != nil {
+		return
+	}
+	if p.err = p.w.WriteByte(c); p.err == nil {
+		p.n++
+	}
+}
+
+func (p *printer) writeInt(i int64) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(strconv.AppendInt(p.buf[:0], i, 10))
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *printer) writeUint(i uint64) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(strconv.AppendUint(p.buf[:0], i, 10))
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *printer) sexp(sexp Sexp) {
+	switch s := sexp.(type) {
+	case *SList:
+		p.writeByte('(')
+		for i, child := range s.List {
+			if i > 0 {
+				p.writeByte(' ')
+			}
+			p.sexp(child)
+		}
+		p.writeByte(')')
+	case *SSymbol:
+		if isSimpleSymbol(s.Symbol) {
+			p.writeString(s.Symbol)
+		} else {
+			p.writeByte('|')
+			p.writeString(s.Symbol)
+			p.writeByte('|')
+		}
+	case *SString:
+		p.writeByte('"')
+		p.writeString(strings.Replace(s.Str, `"`, `""`, -1))
+		p.writeByte('"')
+	case *SKeyword:
+		p.writeByte(':')
+		p.writeString(s.Keyword)
+	case *SInt:
+		p.writeInt(s.Int)
+	case *SBigInt:
+		p.writeString(s.Int.String())
+	case *SDecimal:
+		p.writeString(s.Decimal)
+	case *SBitVec:
+		p.writeString("(_ bv")
+		p.writeUint(uint64(s.Value))
+		p.writeByte(' ')
+		p.writeInt(s.Width)
+		p.writeByte(')')
+	}
+}
+
+// isSimpleSymbol reports whether sym can be written as is, rather
+// than needing to be quoted with |s.
+func isSimpleSymbol(sym string) bool {
+	if sym == "" || isDigit(sym[0]) {
+		return false
+	}
+	for i := 0; i < len(sym); i++ {
+		c := sym[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', isDigit(c):
+		case strings.IndexByte("~!@$%^&*_-+=<>.?/", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
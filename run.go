@@ -0,0 +1,250 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RunScript executes each command of the SMT-LIB script read from r
+// against s, writing the responses to out as a solver would.  Commands
+// with a corresponding Solver method are run through that method;
+// everything else is sent with Command.  As with a real solver,
+// errors from s are written to out as (error "...") responses and
+// the script continues; only problems reading the script or writing
+// out are returned.
+//
+// print-success is handled here rather than by s, which may need
+// it for its own use, and defaults to false.
+func RunScript(s Solver, r io.Reader, out io.Writer) error {
+	run := &scriptRunner{
+		s:     s,
+		out:   bufio.NewWriter(out),
+		sorts: make(map[string]Sort),
+	}
+	p := NewParser(r)
+	for {
+		sexp, err := p.Next()
+		if err == ParserEOF {
+			break
+		} else if err != nil {
+			run.error(err)
+			run.out.Flush()
+			return err
+		}
+		cmd, err := SexpToCommand(sexp)
+		if err != nil {
+			pos, _ := p.Pos(sexp)
+			err = &ParseError{Position: pos, Token: commandName(sexp), Message: err.Error()}
+			run.error(err)
+			run.out.Flush()
+			return err
+		}
+		if _, ok := cmd.(*Exit); ok {
+			break
+		}
+		run.run(cmd)
+		// flush after every command, so that interactive use
+		// works as expected
+		if err = run.out.Flush(); err != nil {
+			return err
+		}
+	}
+	return run.out.Flush()
+}
+
+type scriptRunner struct {
+	s            Solver
+	out          *bufio.Writer
+	printSuccess bool
+	sorts        map[string]Sort // of declared constants, for models
+}
+
+func (run *scriptRunner) respond(sexp Sexp) {
+	sexp.WriteTo(run.out)
+	run.out.WriteByte('\n')
+}
+
+func (run *scriptRunner) success() {
+	if run.printSuccess {
+		run.out.WriteString("success\n")
+	}
+}
+
+func (run *scriptRunner) error(err error) {
+	run.respond(&SList{[]Sexp{&SSymbol{"error"}, &SString{err.Error()}}})
+}
+
+func (run *scriptRunner) run(cmd Command) {
+	var err error
+	switch c := cmd.(type) {
+	case *SetLogic:
+		if err = run.s.SetLogic(c.Logic); err == nil {
+			run.success()
+			return
+		}
+	case *SetOption:
+		if c.Name == "print-success" {
+			run.printSuccess = IsSymbol(c.Value, "true")
+			run.success()
+			return
+		}
+		// values we can't type, like lists, are sent as is
+		if v, verr := SexpToOptionValue(c.Value); verr == nil {
+			if err = run.s.SetOption(Option(c.Name), v); err == nil {
+				run.success()
+				return
+			}
+		}
+	case *SetInfo:
+		if v, verr := SexpToOptionValue(c.Value); verr == nil {
+			if err = run.s.SetInfo(Info(c.Name), v); err == nil {
+				run.success()
+				return
+			}
+		}
+	case *GetOption:
+		var v OptionValue
+		if v, err = run.s.GetOption(Option(c.Name)); err == nil {
+			run.respond(OptionValueToSexp(v))
+			return
+		}
+	case *GetInfo:
+		var v Sexp
+		if v, err = run.s.GetInfo(Info(c.Name)); err == nil {
+			run.respond(&SList{[]Sexp{&SKeyword{c.Name}, v}})
+			return
+		}
+	case *DeclareConst:
+		if err = run.s.DeclareConst(string(c.Id), c.Sort); err == nil {
+			run.sorts[string(c.Id)] = c.Sort
+			run.success()
+			return
+		}
+	case *DeclareFun:
+		if len(c.Params) == 0 {
+			run.sorts[string(c.Id)] = c.Result
+		}
+	case *Assert:
+		if err = run.s.Assert(c.Term); err == nil {
+			run.success()
+			return
+		}
+	case *Push:
+		for i := 0; i < c.Levels; i++ {
+			run.s.Push()
+		}
+		run.success()
+		return
+	case *Pop:
+		for i := 0; i < c.Levels && err == nil; i++ {
+			err = run.s.Pop()
+		}
+		if err == nil {
+			run.success()
+			return
+		}
+	case *CheckSat:
+		var result Satisfiable
+		if result, err = run.s.CheckSat(); err == nil {
+			switch result {
+			case Sat:
+				run.out.WriteString("sat\n")
+			case Unsat:
+				run.out.WriteString("unsat\n")
+			default:
+				run.out.WriteString("unknown\n")
+			}
+			return
+		}
+	case *GetModel:
+		var model map[string]Term
+		if model, err = run.s.GetModel(); err == nil {
+			run.model(model)
+			return
+		}
+	}
+
+	if err != nil {
+		run.error(err)
+		return
+	}
+
+	r, err := run.s.Command(CommandToSexp(cmd))
+	if err != nil {
+		run.error(err)
+		return
+	}
+	if IsSymbol(r, "success") {
+		run.success()
+		return
+	}
+	run.respond(r)
+}
+
+func (run *scriptRunner) model(model map[string]Term) {
+	names := make([]string, 0, len(model))
+	for name := range model {
+		names = append(names, name)
+		if run.sortOf(name, model[name]) == nil {
+			// declared some way we don't track, like
+			// declare-datatypes; let the solver print its
+			// model itself.
+			r, err := run.s.Command(CommandToSexp(&GetModel{}))
+			if err != nil {
+				run.error(err)
+			} else {
+				run.respond(r)
+			}
+			return
+		}
+	}
+	sort.Strings(names)
+
+	run.out.WriteString("(\n")
+	for _, name := range names {
+		fmt.Fprintf(run.out, "  %s\n", &SList{[]Sexp{
+			&SSymbol{"define-fun"},
+			&SSymbol{name},
+			&SList{[]Sexp{}},
+			SortToSexp(run.sortOf(name, model[name])),
+			TermToSexp(model[name]),
+		}})
+	}
+	run.out.WriteString(")\n")
+}
+
+// sortOf returns the sort of the constant name, from its declaration
+// or from its value, or nil if it can't be determined.
+func (run *scriptRunner) sortOf(name string, value Term) Sort {
+	if s, ok := run.sorts[name]; ok {
+		return s
+	}
+	switch v := value.(type) {
+	case *Int, *BigInt:
+		return IntSort
+	case *Decimal:
+		return &SortName{"Real"}
+	case *BitVec:
+		return &BitVecSort{v.Width}
+	case *IndexedApp:
+		if strings.HasPrefix(string(v.Id), "bv") && len(v.Indices) == 1 && len(v.Args) == 0 {
+			return &BitVecSort{v.Indices[0]}
+		}
+	case *String:
+		return &SortName{"String"}
+	case *Const:
+		if v.Id == "true" || v.Id == "false" {
+			return BoolSort
+		}
+	case *As:
+		return v.Sort
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package smt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// scriptSolver is a minimal Solver: it is unsat exactly when false
+// has been asserted, and its models assign every constant its
+// declaration order.
+type scriptSolver struct {
+	consts   []string
+	asserted [][]Term
+	commands []string
+	options  map[Option]OptionValue
+}
+
+func (s *scriptSolver) Close() {}
+
+func (s *scriptSolver) Capabilities() Capabilities {
+	return Capabilities{Name: "script"}
+}
+
+func (s *scriptSolver) DeclareConst(id string, sort Sort) error {
+	for _, c := range s.consts {
+		if c == id {
+			return fmt.Errorf("%s already declared", id)
+		}
+	}
+	s.consts = append(s.consts, id)
+	return nil
+}
+
+func (s *scriptSolver) Assert(t Term) error {
+	s.asserted[len(s.asserted)-1] = append(s.asserted[len(s.asserted)-1], t)
+	return nil
+}
+
+func (s *scriptSolver) CheckSat() (Satisfiable, error) {
+	for _, level := range s.asserted {
+		for _, t := range level {
+			if IsSymbol(TermToSexp(t), "false") {
+				return Unsat, nil
+			}
+		}
+	}
+	return Sat, nil
+}
+
+func (s *scriptSolver) CheckSatLimited(limits Limits) (Satisfiable, string, error) {
+	result, err := s.CheckSat()
+	return result, "", err
+}
+
+func (s *scriptSolver) ReasonUnknown() string {
+	return ""
+}
+
+func (s *scriptSolver) Statistics() (Statistics, error) {
+	return Statistics{}, nil
+}
+
+func (s *scriptSolver) GetModel() (map[string]Term, error) {
+	model := make(map[string]Term)
+	for i, c := range s.consts {
+		model[c] = NewInt(i)
+	}
+	return model, nil
+}
+
+func (s *scriptSolver) Push() {
+	s.asserted = append(s.asserted, nil)
+}
+
+func (s *scriptSolver) Pop() error {
+	if len(s.asserted) == 1 {
+		return fmt.Errorf("pop: empty stack")
+	}
+	s.asserted = s.asserted[:len(s.asserted)-1]
+	return nil
+}
+
+func (s *scriptSolver) Assertions() []Term {
+	var all []Term
+	for _, level := range s.asserted {
+		all = append(all, level...)
+	}
+	return all
+}
+
+func (s *scriptSolver) SetLogic(logic string) error {
+	s.commands = append(s.commands, CommandToSexp(&SetLogic{logic}).String())
+	return nil
+}
+
+func (s *scriptSolver) SetOption(opt Option, value OptionValue) error {
+	if s.options == nil {
+		s.options = make(map[Option]OptionValue)
+	}
+	s.options[opt] = value
+	return nil
+}
+
+func (s *scriptSolver) GetOption(opt Option) (OptionValue, error) {
+	if v, ok := s.options[opt]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown option %s", opt)
+}
+
+func (s *scriptSolver) SetInfo(info Info, value OptionValue) error {
+	return nil
+}
+
+func (s *scriptSolver) GetInfo(info Info) (Sexp, error) {
+	if info == InfoName {
+		return &SString{"script"}, nil
+	}
+	return nil, fmt.Errorf("unsupported info %s", info)
+}
+
+func (s *scriptSolver) Command(sexp Sexp) (Sexp, error) {
+	s.commands = append(s.commands, sexp.String())
+	if IsSymbol(sexp.(*SList).List[0], "echo") {
+		return sexp.(*SList).List[1], nil
+	}
+	return &SSymbol{"success"}, nil
+}
+
+func TestRunScript(t *testing.T) {
+	script := `(set-logic QF_LIA)
+(declare-const x Int)
+(declare-const y Int)
+(push 1)
+(assert false)
+(check-sat)
+(pop 1)
+(pop 1)
+(check-sat)
+(set-option :print-success true)
+(declare-const x Int)
+(get-model)
+(set-option :random-seed 7)
+(get-option :random-seed)
+(get-option :verbosity)
+(get-info :name)
+(set-info :status sat)
+(echo "done")
+(exit)
+(check-sat)
+`
+	s := &scriptSolver{asserted: [][]Term{nil}}
+	var out bytes.Buffer
+	if err := RunScript(s, strings.NewReader(script), &out); err != nil {
+		t.Fatalf("RunScript: %s", err)
+	}
+
+	expected := `unsat
+(error "pop: empty stack")
+sat
+success
+(error "x already declared")
+(
+  (define-fun x () Int 0)
+  (define-fun y () Int 1)
+)
+success
+7
+(error "unknown option verbosity")
+(:name "script")
+success
+"done"
+`
+	if out.String() != expected {
+		t.Fatalf("RunScript output:\n%s\n!=\n%s", out.String(), expected)
+	}
+	if len(s.commands) != 2 || s.commands[0] != "(set-logic QF_LIA)" {
+		t.Fatalf("unexpected raw commands: %v", s.commands)
+	}
+
+	out.Reset()
+	err := RunScript(s, strings.NewReader("(check-sat)\n(assert"), &out)
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("expected *ParseError, not %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "sat\n(error ") {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+}
@@ -0,0 +1,477 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Command is a single SMT-LIB script command.
+type Command interface {
+	command()
+}
+
+type SetLogic struct {
+	Logic string
+}
+
+// SetOption sets the option Name (a keyword, without the leading
+// colon) to Value.
+type SetOption struct {
+	Name  string
+	Value Sexp
+}
+
+type SetInfo struct {
+	Name  string
+	Value Sexp
+}
+
+type GetInfo struct {
+	Name string
+}
+
+type GetOption struct {
+	Name string
+}
+
+type DeclareSort struct {
+	Id    Identifier
+	Arity int
+}
+
+type DeclareConst struct {
+	Id   Identifier
+	Sort Sort
+}
+
+type DeclareFun struct {
+	Id     Identifier
+	Params []Sort
+	Result Sort
+}
+
+type DefineFun struct {
+	Id     Identifier
+	Params []SortedVar
+	Result Sort
+	Body   Term
+}
+
+type Assert struct {
+	Term Term
+}
+
+type CheckSat struct{}
+
+type CheckSatAssuming struct {
+	Assumptions []Term
+}
+
+type Push struct {
+	Levels int
+}
+
+type Pop struct {
+	Levels int
+}
+
+type GetModel struct{}
+
+type GetValue struct {
+	Terms []Term
+}
+
+type GetAssertions struct{}
+
+type GetUnsatCore struct{}
+
+type Echo struct {
+	Text string
+}
+
+type Reset struct{}
+
+type ResetAssertions struct{}
+
+type Exit struct{}
+
+// RawCommand is a command, like declare-datatypes, that doesn't have
+// a typed representation.
+type RawCommand struct {
+	Sexp *SList
+}
+
+func (*SetLogic) command()         {}
+func (*SetOption) command()        {}
+func (*SetInfo) command()          {}
+func (*GetInfo) command()          {}
+func (*GetOption) command()        {}
+func (*DeclareSort) command()      {}
+func (*DeclareConst) command()     {}
+func (*DeclareFun) command()       {}
+func (*DefineFun) command()        {}
+func (*Assert) command()           {}
+func (*CheckSat) command()         {}
+func (*CheckSatAssuming) command() {}
+func (*Push) command()             {}
+func (*Pop) command()              {}
+func (*GetModel) command()         {}
+func (*GetValue) command()         {}
+func (*GetAssertions) command()    {}
+func (*GetUnsatCore) command()     {}
+func (*Echo) command()             {}
+func (*Reset) command()            {}
+func (*ResetAssertions) command()  {}
+func (*Exit) command()             {}
+func (*RawCommand) command()       {}
+
+// ParseScript reads every command in an SMT-LIB script.  Errors
+// are returned as a *ParseError pointing at the offending command.
+func ParseScript(r io.Reader) ([]Command, error) {
+	var cmds []Command
+	p := NewParser(r)
+	for {
+		sexp, err := p.Next()
+		if err == ParserEOF {
+			return cmds, nil
+		} else if err != nil {
+			return nil, err
+		}
+		cmd, err := SexpToCommand(sexp)
+		if err != nil {
+			pos, _ := p.Pos(sexp)
+			return nil, &ParseError{
+				Position: pos,
+				Token:    commandName(sexp),
+				Message:  err.Error(),
+			}
+		}
+		cmds = append(cmds, cmd)
+	}
+}
+
+// WriteScript writes cmds to w, one per line.
+func WriteScript(w io.Writer, cmds []Command) error {
+	bw := bufio.NewWriter(w)
+	for _, cmd := range cmds {
+		CommandToSexp(cmd).WriteTo(bw)
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+func commandName(sexp Sexp) string {
+	if list, ok := sexp.(*SList); ok && len(list.List) > 0 {
+		if sym, ok := list.List[0].(*SSymbol); ok {
+			return sym.Symbol
+		}
+	}
+	return sexp.String()
+}
+
+func SexpToCommand(sexp Sexp) (Command, error) {
+	list, ok := sexp.(*SList)
+	if !ok || len(list.List) == 0 {
+		return nil, fmt.Errorf("expected command, not '%s'", sexp)
+	}
+	head, ok := list.List[0].(*SSymbol)
+	if !ok {
+		return nil, fmt.Errorf("expected command, not '%s'", sexp)
+	}
+	args := list.List[1:]
+
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%s: expected %d arguments, not %d", head.Symbol, n, len(args))
+		}
+		return nil
+	}
+
+	switch head.Symbol {
+	case "set-logic":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		logic, err := symbolArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &SetLogic{logic}, nil
+	case "set-option", "set-info":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		kw, ok := args[0].(*SKeyword)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected keyword, not '%s'", head.Symbol, args[0])
+		}
+		if head.Symbol == "set-option" {
+			return &SetOption{kw.Keyword, args[1]}, nil
+		}
+		return &SetInfo{kw.Keyword, args[1]}, nil
+	case "get-info", "get-option":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		kw, ok := args[0].(*SKeyword)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected keyword, not '%s'", head.Symbol, args[0])
+		}
+		if head.Symbol == "get-info" {
+			return &GetInfo{kw.Keyword}, nil
+		}
+		return &GetOption{kw.Keyword}, nil
+	case "declare-sort":
+		if len(args) != 1 {
+			if err := arity(2); err != nil {
+				return nil, err
+			}
+		}
+		id, err := symbolArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		if len(args) == 2 {
+			arity, ok := args[1].(*SInt)
+			if !ok {
+				return nil, fmt.Errorf("declare-sort: expected arity, not '%s'", args[1])
+			}
+			n = arity.Int
+		}
+		return &DeclareSort{Identifier(id), int(n)}, nil
+	case "declare-const":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		id, err := symbolArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sort, err := SexpToSort(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &DeclareConst{Identifier(id), sort}, nil
+	case "declare-fun":
+		if err := arity(3); err != nil {
+			return nil, err
+		}
+		id, err := symbolArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		params, ok := args[1].(*SList)
+		if !ok {
+			return nil, fmt.Errorf("declare-fun: expected parameter sorts, not '%s'", args[1])
+		}
+		sorts := make([]Sort, 0, len(params.List))
+		for _, p := range params.List {
+			sort, err := SexpToSort(p)
+			if err != nil {
+				return nil, err
+			}
+			sorts = append(sorts, sort)
+		}
+		result, err := SexpToSort(args[2])
+		if err != nil {
+			return nil, err
+		}
+		return &DeclareFun{Identifier(id), sorts, result}, nil
+	case "define-fun":
+		if err := arity(4); err != nil {
+			return nil, err
+		}
+		id, err := symbolArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		var params []SortedVar
+		if list, ok := args[1].(*SList); !ok || len(list.List) > 0 {
+			if params, err = sexpToSortedVars(args[1]); err != nil {
+				return nil, err
+			}
+		}
+		result, err := SexpToSort(args[2])
+		if err != nil {
+			return nil, err
+		}
+		body, err := SexpToTerm(args[3])
+		if err != nil {
+			return nil, err
+		}
+		return &DefineFun{Identifier(id), params, result, body}, nil
+	case "assert":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		t, err := SexpToTerm(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Assert{t}, nil
+	case "check-sat":
+		if err := arity(0); err != nil {
+			return nil, err
+		}
+		return &CheckSat{}, nil
+	case "check-sat-assuming":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		terms, err := termsArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &CheckSatAssuming{terms}, nil
+	case "push", "pop":
+		levels := 1
+		if len(args) == 1 {
+			n, ok := args[0].(*SInt)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected numeral, not '%s'", head.Symbol, args[0])
+			}
+			levels = int(n.Int)
+		} else if err := arity(0); err != nil {
+			return nil, err
+		}
+		if head.Symbol == "push" {
+			return &Push{levels}, nil
+		}
+		return &Pop{levels}, nil
+	case "get-model", "get-assertions", "get-unsat-core", "reset", "reset-assertions", "exit":
+		if err := arity(0); err != nil {
+			return nil, err
+		}
+		switch head.Symbol {
+		case "get-model":
+			return &GetModel{}, nil
+		case "get-assertions":
+			return &GetAssertions{}, nil
+		case "get-unsat-core":
+			return &GetUnsatCore{}, nil
+		case "reset":
+			return &Reset{}, nil
+		case "reset-assertions":
+			return &ResetAssertions{}, nil
+		default:
+			return &Exit{}, nil
+		}
+	case "get-value":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		terms, err := termsArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return &GetValue{terms}, nil
+	case "echo":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		s, ok := args[0].(*SString)
+		if !ok {
+			return nil, fmt.Errorf("echo: expected string, not '%s'", args[0])
+		}
+		return &Echo{s.Str}, nil
+	}
+	return &RawCommand{list}, nil
+}
+
+func symbolArg(sexp Sexp) (string, error) {
+	sym, ok := sexp.(*SSymbol)
+	if !ok {
+		return "", fmt.Errorf("expected symbol, not '%s'", sexp)
+	}
+	return sym.Symbol, nil
+}
+
+func termsArg(sexp Sexp) ([]Term, error) {
+	list, ok := sexp.(*SList)
+	if !ok {
+		return nil, fmt.Errorf("expected list of terms, not '%s'", sexp)
+	}
+	terms := make([]Term, 0, len(list.List))
+	for _, s := range list.List {
+		t, err := SexpToTerm(s)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+func termsToSexp(terms []Term) Sexp {
+	list := make([]Sexp, 0, len(terms))
+	for _, t := range terms {
+		list = append(list, TermToSexp(t))
+	}
+	return &SList{list}
+}
+
+func cmdSexp(name string, args ...Sexp) Sexp {
+	return &SList{append([]Sexp{&SSymbol{name}}, args...)}
+}
+
+func CommandToSexp(cmd Command) Sexp {
+	switch c := cmd.(type) {
+	case *SetLogic:
+		return cmdSexp("set-logic", &SSymbol{c.Logic})
+	case *SetOption:
+		return cmdSexp("set-option", &SKeyword{c.Name}, c.Value)
+	case *SetInfo:
+		return cmdSexp("set-info", &SKeyword{c.Name}, c.Value)
+	case *GetInfo:
+		return cmdSexp("get-info", &SKeyword{c.Name})
+	case *GetOption:
+		return cmdSexp("get-option", &SKeyword{c.Name})
+	case *DeclareSort:
+		return cmdSexp("declare-sort", IdToSexp(c.Id), &SInt{int64(c.Arity)})
+	case *DeclareConst:
+		return cmdSexp("declare-const", IdToSexp(c.Id), SortToSexp(c.Sort))
+	case *DeclareFun:
+		params := make([]Sexp, 0, len(c.Params))
+		for _, p := range c.Params {
+			params = append(params, SortToSexp(p))
+		}
+		return cmdSexp("declare-fun", IdToSexp(c.Id), &SList{params}, SortToSexp(c.Result))
+	case *DefineFun:
+		return cmdSexp("define-fun", IdToSexp(c.Id), sortedVarsToSexp(c.Params), SortToSexp(c.Result), TermToSexp(c.Body))
+	case *Assert:
+		return cmdSexp("assert", TermToSexp(c.Term))
+	case *CheckSat:
+		return cmdSexp("check-sat")
+	case *CheckSatAssuming:
+		return cmdSexp("check-sat-assuming", termsToSexp(c.Assumptions))
+	case *Push:
+		return cmdSexp("push", &SInt{int64(c.Levels)})
+	case *Pop:
+		return cmdSexp("pop", &SInt{int64(c.Levels)})
+	case *GetModel:
+		return cmdSexp("get-model")
+	case *GetValue:
+		return cmdSexp("get-value", termsToSexp(c.Terms))
+	case *GetAssertions:
+		return cmdSexp("get-assertions")
+	case *GetUnsatCore:
+		return cmdSexp("get-unsat-core")
+	case *Echo:
+		return cmdSexp("echo", &SString{c.Text})
+	case *Reset:
+		return cmdSexp("reset")
+	case *ResetAssertions:
+		return cmdSexp("reset-assertions")
+	case *Exit:
+		return cmdSexp("exit")
+	case *RawCommand:
+		return c.Sexp
+	}
+	panic("unreachable")
+}
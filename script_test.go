@@ -0,0 +1,94 @@
+package smt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testScript = `; a comment
+(set-logic QF_LIA)
+(set-option :produce-models true)
+(set-info :source |a quoted source|)
+(declare-sort U 0)
+(declare-const x Int)
+(declare-fun f (Int U) Bool)
+(define-fun g ((a Int) (b Int)) Int (+ a b))
+(define-fun two () Real 2.0)
+(assert (let ((y (* x 2)) (z 1)) (> (g y z) 3)))
+(assert (forall ((u U)) (f x u)))
+(push 1)
+(check-sat-assuming ((< x 0)))
+(pop 1)
+(check-sat)
+(get-value (x (g x 1)))
+(get-model)
+(declare-datatypes ((P 0)) (((mk-p (p1 Int)))))
+(assert (! (> x 0) :named a1 :weight 2 :flag))
+(echo "done")
+(exit)
+`
+
+func TestParseScript(t *testing.T) {
+	cmds, err := ParseScript(strings.NewReader(testScript))
+	if err != nil {
+		t.Fatalf("ParseScript: %s", err)
+	}
+	if len(cmds) != 20 {
+		t.Fatalf("expected 20 commands, not %d", len(cmds))
+	}
+
+	x := NewConst("x")
+	expected := map[int]Command{
+		0:  &SetLogic{"QF_LIA"},
+		5:  &DeclareFun{"f", []Sort{IntSort, &SortName{"U"}}, BoolSort},
+		6:  &DefineFun{"g", []SortedVar{{"a", IntSort}, {"b", IntSort}}, IntSort, Add(NewConst("a"), NewConst("b"))},
+		7:  &DefineFun{"two", nil, &SortName{"Real"}, &Decimal{"2.0"}},
+		8:  &Assert{&Let{"y", Mul(x, NewInt(2)), &Let{"z", NewInt(1), GT(NewApp("g", NewConst("y"), NewConst("z")), NewInt(3))}}},
+		9:  &Assert{&Forall{[]SortedVar{{"u", &SortName{"U"}}}, NewApp("f", x, NewConst("u"))}},
+		10: &Push{1},
+		11: &CheckSatAssuming{[]Term{LT(x, NewInt(0))}},
+		13: &CheckSat{},
+		17: &Assert{&Annotated{GT(x, NewInt(0)), []Attribute{{"named", &SSymbol{"a1"}}, {"weight", &SInt{2}}, {"flag", nil}}}},
+		18: &Echo{"done"},
+	}
+	for i, cmd := range expected {
+		if !reflect.DeepEqual(cmds[i], cmd) {
+			t.Fatalf("command %d: %#v != %#v", i, cmds[i], cmd)
+		}
+	}
+	if _, ok := cmds[16].(*RawCommand); !ok {
+		t.Fatalf("expected RawCommand, not %#v", cmds[16])
+	}
+
+	// writing the script back out and reading it in again gives the
+	// same commands
+	var buf bytes.Buffer
+	if err = WriteScript(&buf, cmds); err != nil {
+		t.Fatalf("WriteScript: %s", err)
+	}
+	rt, err := ParseScript(&buf)
+	if err != nil {
+		t.Fatalf("ParseScript(WriteScript): %s", err)
+	}
+	if !reflect.DeepEqual(rt, cmds) {
+		t.Fatalf("round trip differs")
+	}
+}
+
+func TestParseScriptError(t *testing.T) {
+	_, err := ParseScript(strings.NewReader("(check-sat)\n  (declare-const x)\n"))
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, not %#v", err)
+	}
+	if perr.Line != 2 || perr.Column != 3 || perr.Token != "declare-const" {
+		t.Fatalf("unexpected error: %s", perr)
+	}
+
+	_, err = ParseScript(strings.NewReader("(assert (let ((a 1) (b a)) b))"))
+	if err == nil {
+		t.Fatalf("expected error for parallel let")
+	}
+}
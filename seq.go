@@ -0,0 +1,72 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+)
+
+// Sequences, (Seq T), are an extension implemented by both Z3 and
+// cvc5 rather than part of the SMT-LIB standard.
+
+func SeqSort(elem Sort) Sort {
+	return &SortApp{"Seq", []Sort{elem}}
+}
+
+func SeqEmpty(elem Sort) Term {
+	return &As{"seq.empty", SeqSort(elem)}
+}
+
+func SeqUnit(a Term) Term {
+	return NewApp("seq.unit", a)
+}
+
+func SeqConcat(a, b Term, rest ...Term) Term {
+	return NewApp("seq.++", append([]Term{a, b}, rest...)...)
+}
+
+func SeqLen(s Term) Term {
+	return NewApp("seq.len", s)
+}
+
+func SeqNth(s, i Term) Term {
+	return NewApp("seq.nth", s, i)
+}
+
+func SeqExtract(s, offset, length Term) Term {
+	return NewApp("seq.extract", s, offset, length)
+}
+
+// SeqToSlice decodes a sequence value, as returned in a model, into
+// its elements.  Values are built from seq.empty, seq.unit and
+// seq.++; anything else is an error.
+func SeqToSlice(t Term) ([]Term, error) {
+	return appendSeq(nil, t)
+}
+
+func appendSeq(elems []Term, t Term) ([]Term, error) {
+	switch v := t.(type) {
+	case *As:
+		if v.Id == "seq.empty" {
+			return elems, nil
+		}
+	case *App:
+		switch v.Id {
+		case "seq.unit":
+			if len(v.Args) == 1 {
+				return append(elems, v.Args[0]), nil
+			}
+		case "seq.++":
+			var err error
+			for _, arg := range v.Args {
+				if elems, err = appendSeq(elems, arg); err != nil {
+					return nil, err
+				}
+			}
+			return elems, nil
+		}
+	}
+	return nil, fmt.Errorf("not a sequence value: %s", TermToSexp(t))
+}
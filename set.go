@@ -0,0 +1,129 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+)
+
+// Finite sets and relations are a cvc5 extension (see TheorySets);
+// relations are sets of tuples.
+
+func SetSort(elem Sort) Sort {
+	return &SortApp{"Set", []Sort{elem}}
+}
+
+func TupleSort(elems ...Sort) Sort {
+	return &SortApp{"Tuple", elems}
+}
+
+func RelationSort(cols ...Sort) Sort {
+	return SetSort(TupleSort(cols...))
+}
+
+func SetEmpty(elem Sort) Term {
+	return &As{"set.empty", SetSort(elem)}
+}
+
+func SetUniverse(elem Sort) Term {
+	return &As{"set.universe", SetSort(elem)}
+}
+
+func SetSingleton(a Term) Term {
+	return NewApp("set.singleton", a)
+}
+
+// SetInsert adds elems to the set s.
+func SetInsert(s Term, elems ...Term) Term {
+	args := make([]Term, 0, len(elems)+1)
+	args = append(append(args, elems...), s)
+	return NewApp("set.insert", args...)
+}
+
+func SetUnion(a, b Term) Term {
+	return NewApp("set.union", a, b)
+}
+
+func SetInter(a, b Term) Term {
+	return NewApp("set.inter", a, b)
+}
+
+func SetMinus(a, b Term) Term {
+	return NewApp("set.minus", a, b)
+}
+
+func SetComplement(a Term) Term {
+	return NewApp("set.complement", a)
+}
+
+func SetMember(x, s Term) Term {
+	return NewApp("set.member", x, s)
+}
+
+func SetSubset(a, b Term) Term {
+	return NewApp("set.subset", a, b)
+}
+
+func SetCard(s Term) Term {
+	return NewApp("set.card", s)
+}
+
+func Tuple(elems ...Term) Term {
+	return NewApp("tuple", elems...)
+}
+
+func RelJoin(a, b Term) Term {
+	return NewApp("rel.join", a, b)
+}
+
+func RelProduct(a, b Term) Term {
+	return NewApp("rel.product", a, b)
+}
+
+func RelTranspose(a Term) Term {
+	return NewApp("rel.transpose", a)
+}
+
+func RelTClosure(a Term) Term {
+	return NewApp("rel.tclosure", a)
+}
+
+// SetToSlice decodes a set value, as returned in a model, into its
+// elements.  Values are built from set.empty, set.singleton,
+// set.insert and set.union; anything else is an error.  Elements of
+// a relation are tuple applications.
+func SetToSlice(t Term) ([]Term, error) {
+	return appendSet(nil, t)
+}
+
+func appendSet(elems []Term, t Term) ([]Term, error) {
+	switch v := t.(type) {
+	case *As:
+		if v.Id == "set.empty" {
+			return elems, nil
+		}
+	case *App:
+		switch v.Id {
+		case "set.singleton":
+			if len(v.Args) == 1 {
+				return append(elems, v.Args[0]), nil
+			}
+		case "set.insert":
+			if len(v.Args) >= 2 {
+				elems = append(elems, v.Args[:len(v.Args)-1]...)
+				return appendSet(elems, v.Args[len(v.Args)-1])
+			}
+		case "set.union":
+			var err error
+			for _, arg := range v.Args {
+				if elems, err = appendSet(elems, arg); err != nil {
+					return nil, err
+				}
+			}
+			return elems, nil
+		}
+	}
+	return nil, fmt.Errorf("not a set value: %s", TermToSexp(t))
+}
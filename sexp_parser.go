@@ -4,47 +4,196 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"strconv"
+	"strings"
 )
 
 var ParserEOF = errors.New("End-of-Input")
 
-type Parser struct {
-	sexps chan Sexp
-	errs  chan error
+// Position is a location in a Parser's input.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // byte column, starting at 1
 }
 
-func (p *Parser) Read() (Sexp, error) {
-	select {
-	case s := <-p.sexps:
-		return s, nil
-	case err := <-p.errs:
-		return nil, err
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// ParseError describes a syntax error in a Parser's input.
+type ParseError struct {
+	Position        // where the offending token starts
+	Token    string // the offending token, empty at the end of input
+	Message  string
+	Source   string // the line of input containing the error, if known
+}
+
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s: %s", e.Position, e.Message)
+	}
+	return fmt.Sprintf("%s: %s (at %q)", e.Position, e.Message, e.Token)
+}
+
+// Excerpt returns the source line containing the error followed by
+// a line with a caret under the offending token, with tabs expanded
+// to 8 spaces, or "" if the source line isn't known.
+func (e *ParseError) Excerpt() string {
+	if e.Source == "" {
+		return ""
+	}
+	col := e.Column - 1
+	if col > len(e.Source) {
+		col = len(e.Source)
+	}
+	if col < 0 {
+		col = 0
 	}
+	prefix := col + strings.Count(e.Source[:col], "\t")*7
+	source := strings.Replace(e.Source, "\t", "        ", -1)
+	return fmt.Sprintf("%s\n%s^", source, strings.Repeat(" ", prefix))
 }
 
-func (p *Parser) emit(s Sexp) {
-	p.sexps <- s
+// Parser reads a stream of s-expressions.  Parsing happens on the
+// caller's goroutine, reading only as much input as is needed for
+// the next sexp.
+type Parser struct {
+	lex   *smtLex
+	pos   map[Sexp]Position
+	depth int // of parens, for recovering from errors
 }
 
 func NewParser(r io.Reader) *Parser {
-	p := &Parser{
-		sexps: make(chan Sexp),
-		errs:  make(chan error),
+	return &Parser{
+		lex: newSmtLex(r),
+		pos: make(map[Sexp]Position),
 	}
+}
 
-	go p.streamingParse(r)
+// Next returns the next top-level sexp in the input, or ParserEOF
+// once the input is exhausted.  Syntax errors are returned as a
+// *ParseError, after which the parser skips to the end of the
+// enclosing top-level sexp so that Next can be called again to
+// continue with the one after it.  Errors reading the input are
+// returned as-is, and are returned again by every subsequent call.
+func (p *Parser) Next() (Sexp, error) {
+	p.pos = make(map[Sexp]Position)
 
-	return p
+	t, perr := p.lex.lex()
+	if perr == nil && t.kind == iEOF {
+		if p.lex.err != nil {
+			return nil, p.lex.err
+		}
+		return nil, ParserEOF
+	}
+
+	p.depth = 0
+	s, perr := p.parse(t, perr)
+	if p.lex.err != nil {
+		return nil, p.lex.err
+	}
+	if perr != nil {
+		p.recover()
+		return nil, perr
+	}
+	return s, nil
 }
 
-func (p *Parser) streamingParse(r io.Reader) {
-	// this is weird, but without passing in a reference to this
-	// parser object through the lexer, there isn't another good
-	// way to keep the parser and lexer reentrant.
-	err := smtParse(newSmtLex(r, p))
-	if err != 0 {
-		p.errs <- fmt.Errorf("%d parse errors", err)
-	} else {
-		p.errs <- ParserEOF
+// Read is the same as Next.
+func (p *Parser) Read() (Sexp, error) {
+	return p.Next()
+}
+
+// Pos returns the position in the input at which s began.  Positions
+// are only available for the sexp most recently returned by Next and
+// its children.
+func (p *Parser) Pos(s Sexp) (Position, bool) {
+	pos, ok := p.pos[s]
+	return pos, ok
+}
+
+// recover skips the rest of the top-level sexp in which an error
+// occurred.
+func (p *Parser) recover() {
+	for p.depth > 0 {
+		t, _ := p.lex.lex()
+		switch t.kind {
+		case iEOF:
+			return
+		case iLParen:
+			p.depth++
+		case iRParen:
+			p.depth--
+		}
+	}
+}
+
+// parse returns the sexp starting with token t.
+func (p *Parser) parse(t tok, perr *ParseError) (Sexp, *ParseError) {
+	if perr != nil {
+		return nil, perr
+	}
+
+	var s Sexp
+	switch t.kind {
+	case iLParen:
+		p.depth++
+		list := []Sexp{}
+		for {
+			child, perr := p.lex.lex()
+			if perr == nil && child.kind == iRParen {
+				p.depth--
+				break
+			}
+			if perr == nil && child.kind == iEOF {
+				return nil, p.lex.errorf(child, "unexpected end of input, expected ')'")
+			}
+			sexp, perr := p.parse(child, perr)
+			if perr != nil {
+				return nil, perr
+			}
+			list = append(list, sexp)
+		}
+		s = &SList{list}
+	case iRParen:
+		return nil, p.lex.errorf(t, "unexpected ')'")
+	case iInt:
+		if i, err := strconv.ParseInt(t.val, 10, 64); err == nil {
+			s = &SInt{i}
+		} else if n, ok := new(big.Int).SetString(t.val, 10); ok {
+			s = &SBigInt{n}
+		} else {
+			return nil, p.lex.errorf(t, "malformed numeral")
+		}
+	case iDecimal:
+		s = &SDecimal{t.val}
+	case iHex, iBinary:
+		base, bits := 16, 4
+		if t.kind == iBinary {
+			base, bits = 2, 1
+		}
+		digits := t.val[2:]
+		width := int64(len(digits) * bits)
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, p.lex.errorf(t, "malformed bit-vector literal")
+		}
+		if width <= 64 {
+			s = &SBitVec{int64(n.Uint64()), width}
+		} else {
+			// too wide for an SBitVec, so written as SMT-LIB's
+			// other form
+			s = &SList{[]Sexp{&SSymbol{"_"}, &SSymbol{"bv" + n.String()}, &SInt{width}}}
+		}
+	case iString:
+		s = &SString{t.val}
+	case iSymbol:
+		s = &SSymbol{t.val}
+	case iKeyword:
+		s = &SKeyword{t.val}
 	}
+	p.pos[s] = t.pos
+	return s, nil
 }
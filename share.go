@@ -0,0 +1,208 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+)
+
+// TermToSexpShared is like TermToSexp, but compound subterms that
+// are referenced more than once (by pointer, so build terms with a
+// TermManager to share structurally equal subterms) are bound once
+// with let and referred to by name, keeping the output linear in the
+// size of the term's DAG rather than its tree.  Bindings are grouped
+// into nested lets, each of which only refers to names bound by the
+// lets enclosing it.
+//
+// Subterms inside an existing Let or quantifier are printed as they
+// are by TermToSexp, as hoisting them could move references to the
+// bound variables out of scope.
+func TermToSexpShared(t Term) Sexp {
+	p := &sharePrinter{
+		refs:  make(map[Term]int),
+		used:  make(map[Identifier]bool),
+		names: make(map[Term]Identifier),
+		depth: make(map[Term]int),
+	}
+	p.count(t)
+	p.name(t, make(map[Term]bool))
+
+	if len(p.order) == 0 {
+		return TermToSexp(t)
+	}
+
+	levels := make([][]Sexp, 0)
+	for _, shared := range p.order {
+		level := p.level(shared)
+		for len(levels) < level {
+			levels = append(levels, nil)
+		}
+		binding := &SList{[]Sexp{IdToSexp(p.names[shared]), p.expand(shared)}}
+		levels[level-1] = append(levels[level-1], binding)
+	}
+
+	result := p.sexp(t)
+	for i := len(levels) - 1; i >= 0; i-- {
+		result = &SList{[]Sexp{
+			&SSymbol{"let"},
+			&SList{levels[i]},
+			result,
+		}}
+	}
+	return result
+}
+
+type sharePrinter struct {
+	refs  map[Term]int        // number of references to each subterm
+	used  map[Identifier]bool // names we must not shadow
+	names map[Term]Identifier // names of shared subterms
+	order []Term              // shared subterms, children first
+	depth map[Term]int
+	next  int
+}
+
+// children returns the subterms of t that are candidates for
+// sharing.
+func children(t Term) []Term {
+	switch t := t.(type) {
+	case *App:
+		return t.Args
+	case *IndexedApp:
+		return t.Args
+	}
+	return nil
+}
+
+func (p *sharePrinter) count(t Term) {
+	p.refs[t]++
+	if p.refs[t] > 1 {
+		return
+	}
+	switch t := t.(type) {
+	case *Const:
+		p.used[t.Id] = true
+	case *Let, *Forall, *Exists, *Annotated:
+		collectIds(p.used, t)
+	}
+	for _, child := range children(t) {
+		p.count(child)
+	}
+}
+
+func collectIds(used map[Identifier]bool, term Term) {
+	switch t := term.(type) {
+	case *Const:
+		used[t.Id] = true
+	case *Let:
+		used[t.Id] = true
+		collectIds(used, t.Value)
+		collectIds(used, t.In)
+	case *Forall:
+		for _, v := range t.Vars {
+			used[v.Id] = true
+		}
+		collectIds(used, t.Body)
+	case *Exists:
+		for _, v := range t.Vars {
+			used[v.Id] = true
+		}
+		collectIds(used, t.Body)
+	case *Annotated:
+		collectIds(used, t.Term)
+	default:
+		for _, child := range children(t) {
+			collectIds(used, child)
+		}
+	}
+}
+
+func (p *sharePrinter) isShared(t Term) bool {
+	return p.refs[t] > 1 && len(children(t)) > 0
+}
+
+func (p *sharePrinter) name(t Term, visited map[Term]bool) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	for _, child := range children(t) {
+		p.name(child, visited)
+	}
+	if !p.isShared(t) {
+		return
+	}
+	for {
+		p.next++
+		id := Identifier(fmt.Sprintf("_let_%d", p.next))
+		if !p.used[id] {
+			p.names[t] = id
+			break
+		}
+	}
+	p.order = append(p.order, t)
+}
+
+// level returns the nesting depth of the let that must bind the
+// shared subterm t: one more than the deepest shared subterm it
+// refers to.
+func (p *sharePrinter) level(t Term) int {
+	return 1 + p.inner(t)
+}
+
+// inner returns the deepest level of any shared subterm t refers to
+// (not counting t itself).
+func (p *sharePrinter) inner(t Term) int {
+	if d, ok := p.depth[t]; ok {
+		return d
+	}
+	d := 0
+	for _, child := range children(t) {
+		var cd int
+		if p.isShared(child) {
+			cd = p.level(child)
+		} else {
+			cd = p.inner(child)
+		}
+		if cd > d {
+			d = cd
+		}
+	}
+	p.depth[t] = d
+	return d
+}
+
+// sexp converts t, referring to shared subterms by name.
+func (p *sharePrinter) sexp(t Term) Sexp {
+	if id, ok := p.names[t]; ok {
+		return IdToSexp(id)
+	}
+	return p.expand(t)
+}
+
+// expand converts t itself, referring to shared subterms of t by
+// name.
+func (p *sharePrinter) expand(term Term) Sexp {
+	switch t := term.(type) {
+	case *App:
+		args := make([]Sexp, 0, len(t.Args)+1)
+		args = append(args, IdToSexp(t.Id))
+		for _, arg := range t.Args {
+			args = append(args, p.sexp(arg))
+		}
+		return &SList{args}
+	case *IndexedApp:
+		head := TermToSexp(&IndexedApp{t.Id, t.Indices, nil})
+		if len(t.Args) == 0 {
+			return head
+		}
+		args := make([]Sexp, 0, len(t.Args)+1)
+		args = append(args, head)
+		for _, arg := range t.Args {
+			args = append(args, p.sexp(arg))
+		}
+		return &SList{args}
+	}
+	return TermToSexp(term)
+}
@@ -0,0 +1,39 @@
+package smt
+
+import (
+	"testing"
+)
+
+func TestTermToSexpShared(t *testing.T) {
+	x := NewConst("x")
+	a := Add(x, NewInt(1))
+	b := Mul(a, a)
+	term := And(LT(b, NewInt(10)), GT(b, a), Equals(NewConst("_let_1"), x))
+
+	expected := "(let ((_let_2 (+ x 1))) (let ((_let_3 (* _let_2 _let_2))) " +
+		"(and (< _let_3 10) (> _let_3 _let_2) (= _let_1 x))))"
+	s := TermToSexpShared(term).String()
+	if s != expected {
+		t.Fatalf("TermToSexpShared:\n%s !=\n%s", s, expected)
+	}
+
+	// without sharing the output is the same as TermToSexp
+	tree := Add(Mul(x, NewInt(2)), x)
+	if TermToSexpShared(tree).String() != TermToSexp(tree).String() {
+		t.Fatalf("unshared term printed differently: %s", TermToSexpShared(tree))
+	}
+}
+
+func TestTermToSexpSharedLinear(t *testing.T) {
+	tm := NewTermManager()
+	term := tm.NewConst("x")
+	for i := 0; i < 64; i++ {
+		// build the tree twice; the TermManager makes both halves
+		// the same pointer
+		term = tm.Intern(Add(Mul(term, NewInt(2)), Mul(term, NewInt(2))))
+	}
+	s := TermToSexpShared(term).String()
+	if len(s) > 64*100 {
+		t.Fatalf("output not linear in DAG size: %d bytes", len(s))
+	}
+}
@@ -2,6 +2,10 @@ package smt
 
 import (
 	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
 )
 
 type Identifier string
@@ -25,9 +29,32 @@ type Solver interface {
 	DeclareConst(id string, sort Sort) error
 	Assert(t Term) error
 	CheckSat() (Satisfiable, error)
+	// CheckSatLimited is like CheckSat, but bounded by limits.
+	// When the result is Unknown, reason is the solver's
+	// explanation, like "timeout", or "" if it gave none.
+	CheckSatLimited(limits Limits) (result Satisfiable, reason string, err error)
+	// ReasonUnknown returns the reason the last check-sat was
+	// Unknown, or "" if it wasn't or the solver didn't say.
+	ReasonUnknown() string
+	// Statistics returns the solver's statistics, which usually
+	// describe the last check-sat.
+	Statistics() (Statistics, error)
 	GetModel() (map[string]Term, error)
 	Push()
 	Pop() error
+	// Assertions returns the terms currently asserted, at every
+	// level of the assertion stack, outermost first.
+	Assertions() []Term
+
+	SetLogic(logic string) error
+	SetOption(opt Option, value OptionValue) error
+	GetOption(opt Option) (OptionValue, error)
+	SetInfo(info Info, value OptionValue) error
+	// GetInfo returns the value of info, without its keyword.
+	GetInfo(info Info) (Sexp, error)
+
+	// Capabilities describes what the solver supports.
+	Capabilities() Capabilities
 
 	// low-level interface
 	Command(sexp Sexp) (Sexp, error)
@@ -66,11 +93,25 @@ type Int struct {
 	Int int64
 }
 
+// BigInt is an Int literal too large for an Int, like those in
+// models with 128-bit values.
+type BigInt struct {
+	Int *big.Int
+}
+
+// BitVec is a bit-vector literal.  Value holds its bits, so a 64-bit
+// literal with the top bit set has a negative Value.  Literals wider
+// than 64 bits are IndexedApps without arguments, (_ bvN W).
 type BitVec struct {
 	Value int64
 	Width int64
 }
 
+// Decimal is a Real literal, kept in its textual form.
+type Decimal struct {
+	Decimal string
+}
+
 type Const struct {
 	Id Identifier
 }
@@ -86,12 +127,65 @@ type Let struct {
 	In    Term
 }
 
-func (*String) term() {}
-func (*Int) term()    {}
-func (*BitVec) term() {}
-func (*Const) term()  {}
-func (*App) term()    {}
-func (*Let) term()    {}
+// IndexedApp is an application of an indexed function symbol, like
+// ((_ divisible 3) x).  An IndexedApp without Args is the bare
+// indexed identifier (_ Id Indices...).
+type IndexedApp struct {
+	Id      Identifier
+	Indices []int64
+	Args    []Term
+}
+
+type SortedVar struct {
+	Id   Identifier
+	Sort Sort
+}
+
+type Forall struct {
+	Vars []SortedVar
+	Body Term
+}
+
+type Exists struct {
+	Vars []SortedVar
+	Body Term
+}
+
+// As is a qualified identifier, (as id sort), used for constants
+// like seq.empty whose sort can't be inferred from their arguments.
+type As struct {
+	Id   Identifier
+	Sort Sort
+}
+
+// Annotated is a term with attributes, (! Term :kw value ...), most
+// often a :named assertion for unsat cores.  Annotations don't change
+// the term's meaning.
+type Annotated struct {
+	Term       Term
+	Attributes []Attribute
+}
+
+// Attribute is a keyword, without its leading colon, and its value,
+// which is nil if it has none.
+type Attribute struct {
+	Keyword string
+	Value   Sexp
+}
+
+func (*String) term()     {}
+func (*Int) term()        {}
+func (*BigInt) term()     {}
+func (*BitVec) term()     {}
+func (*Const) term()      {}
+func (*App) term()        {}
+func (*Let) term()        {}
+func (*Decimal) term()    {}
+func (*Forall) term()     {}
+func (*Exists) term()     {}
+func (*IndexedApp) term() {}
+func (*As) term()         {}
+func (*Annotated) term()  {}
 
 func NewInt(i int) Term {
 	return &Int{int64(i)}
@@ -117,6 +211,11 @@ func NewApp(x string, args ...Term) Term {
 	return &App{Identifier(x), args}
 }
 
+// Named names t, so that it can appear in unsat cores.
+func Named(t Term, name string) Term {
+	return &Annotated{t, []Attribute{{"named", &SSymbol{name}}}}
+}
+
 func Equals(a, b Term) Term {
 	return NewApp("=", a, b)
 }
@@ -128,31 +227,52 @@ func matchApp(t Term, id Identifier) *App {
 	return nil
 }
 
-func logical(op string, a, b Term) Term {
-	opA := matchApp(a, Identifier(op))
-	opB := matchApp(b, Identifier(op))
+// nary builds an application of the associative operator op,
+// splicing in the arguments of any terms that are themselves
+// applications of op.  With fewer than two terms, the lone term (or
+// unit, the operator's identity) is returned instead.
+func nary(op string, unit Term, terms []Term) Term {
+	switch len(terms) {
+	case 0:
+		return unit
+	case 1:
+		return terms[0]
+	}
 
-	var args []Term
-	switch {
-	case opA != nil && opB != nil:
-		args = append(opA.Args, opB.Args...)
-	case opA != nil:
-		args = append(opA.Args, b)
-	case opB != nil:
-		args = append([]Term{b}, opB.Args...)
-	default:
-		args = []Term{a, b}
+	args := make([]Term, 0, len(terms))
+	for _, t := range terms {
+		if app := matchApp(t, Identifier(op)); app != nil {
+			args = append(args, app.Args...)
+		} else {
+			args = append(args, t)
+		}
 	}
 
 	return NewApp(op, args...)
 }
 
-func And(a, b Term) Term {
-	return logical("and", a, b)
+func Not(a Term) Term {
+	return NewApp("not", a)
+}
+
+func And(terms ...Term) Term {
+	return nary("and", NewBool(true), terms)
+}
+
+func Or(terms ...Term) Term {
+	return nary("or", NewBool(false), terms)
 }
 
-func Or(a, b Term) Term {
-	return logical("or", a, b)
+func Xor(terms ...Term) Term {
+	return nary("xor", NewBool(false), terms)
+}
+
+// Distinct is true when no two terms are equal.
+func Distinct(terms ...Term) Term {
+	if len(terms) < 2 {
+		return NewBool(true)
+	}
+	return NewApp("distinct", terms...)
 }
 
 func IfThenElse(e1, e2, e3 Term) Term {
@@ -163,16 +283,57 @@ func Implies(a, b Term) Term {
 	return NewApp("=>", a, b)
 }
 
-func Add(a, b Term) Term {
-	return NewApp("+", a, b)
+// Add returns the (Int) sum of terms, flattening nested sums.
+func Add(terms ...Term) Term {
+	return nary("+", NewInt(0), terms)
+}
+
+// Sub subtracts rest from a, left to right.  Note that Sub(a) is a,
+// not its negation; use Neg for that.
+func Sub(a Term, rest ...Term) Term {
+	if len(rest) == 0 {
+		return a
+	}
+	args := make([]Term, 0, len(rest)+1)
+	// only binary or wider subtractions can be extended, (- x) is
+	// negation.
+	if app := matchApp(a, "-"); app != nil && len(app.Args) >= 2 {
+		args = append(args, app.Args...)
+	} else {
+		args = append(args, a)
+	}
+	return NewApp("-", append(args, rest...)...)
+}
+
+// Mul returns the (Int) product of terms, flattening nested products.
+func Mul(terms ...Term) Term {
+	return nary("*", NewInt(1), terms)
+}
+
+func Neg(a Term) Term {
+	if i, ok := a.(*Int); ok {
+		// -i.Int overflows for math.MinInt64
+		return bigIntTerm(new(big.Int).Neg(big.NewInt(i.Int)))
+	}
+	return NewApp("-", a)
+}
+
+func Div(a, b Term) Term {
+	return NewApp("div", a, b)
+}
+
+func Mod(a, b Term) Term {
+	return NewApp("mod", a, b)
 }
 
-func Sub(a, b Term) Term {
-	return NewApp("-", a, b)
+func Abs(a Term) Term {
+	return NewApp("abs", a)
 }
 
-func Mul(a, b Term) Term {
-	return NewApp("*", a, b)
+// Divisible is the indexed predicate ((_ divisible n) a), true when
+// n evenly divides a.
+func Divisible(n int64, a Term) Term {
+	return &IndexedApp{"divisible", []int64{n}, []Term{a}}
 }
 
 func LT(a, b Term) Term {
@@ -263,9 +424,12 @@ func BVNot(a Term) Term {
 	return NewApp("bvnot", a)
 }
 
+// Sexp is an s-expression.  String and WriteTo produce the same
+// canonical, single-line representation.
 type Sexp interface {
 	sexp()
 	String() string
+	io.WriterTo
 }
 
 type SList struct {
@@ -288,17 +452,32 @@ type SInt struct {
 	Int int64
 }
 
+// SBigInt is a numeral too large for an SInt.
+type SBigInt struct {
+	Int *big.Int
+}
+
+// SBitVec is a bit-vector literal; like BitVec, Value holds its
+// bits, and is printed unsigned.
 type SBitVec struct {
 	Value int64
 	Width int64
 }
 
+// SDecimal is a decimal literal, like 1.5, kept in its textual form
+// to avoid losing precision.
+type SDecimal struct {
+	Decimal string
+}
+
 func SexpToTerm(sexp Sexp) (Term, error) {
 	switch s := sexp.(type) {
 	case *SString:
 		return &String{s.Str}, nil
 	case *SInt:
 		return &Int{s.Int}, nil
+	case *SBigInt:
+		return &BigInt{s.Int}, nil
 	case *SBitVec:
 		return &BitVec{
 			Value: s.Value,
@@ -306,16 +485,289 @@ func SexpToTerm(sexp Sexp) (Term, error) {
 		}, nil
 	case *SSymbol:
 		return &Const{Identifier(s.Symbol)}, nil
+	case *SDecimal:
+		return &Decimal{s.Decimal}, nil
+	case *SList:
+		return slistToTerm(s)
 	}
 	return nil, fmt.Errorf("unparsable sexp '%s'", sexp)
 }
 
+func slistToTerm(s *SList) (Term, error) {
+	if len(s.List) == 0 {
+		return nil, fmt.Errorf("unparsable empty list")
+	}
+	switch {
+	case IsSymbol(s.List[0], "_"):
+		// the only indexed constant we understand is (_ bvN W)
+		if len(s.List) == 3 {
+			name, ok1 := s.List[1].(*SSymbol)
+			width, ok2 := s.List[2].(*SInt)
+			if ok1 && ok2 && strings.HasPrefix(name.Symbol, "bv") {
+				v, err := strconv.ParseUint(name.Symbol[2:], 10, 64)
+				if err == nil && width.Int <= 64 {
+					return &BitVec{int64(v), width.Int}, nil
+				}
+			}
+		}
+		return indexedToTerm(s, nil)
+	case IsSymbol(s.List[0], "let"):
+		return letToTerm(s)
+	case IsSymbol(s.List[0], "!"):
+		return annotatedToTerm(s)
+	case IsSymbol(s.List[0], "forall"), IsSymbol(s.List[0], "exists"):
+		if len(s.List) != 3 {
+			return nil, fmt.Errorf("malformed quantifier '%s'", s)
+		}
+		vars, err := sexpToSortedVars(s.List[1])
+		if err != nil {
+			return nil, err
+		}
+		body, err := SexpToTerm(s.List[2])
+		if err != nil {
+			return nil, err
+		}
+		if IsSymbol(s.List[0], "forall") {
+			return &Forall{vars, body}, nil
+		}
+		return &Exists{vars, body}, nil
+	case IsSymbol(s.List[0], "as"):
+		if len(s.List) != 3 {
+			return nil, fmt.Errorf("malformed qualified identifier '%s'", s)
+		}
+		id, ok := s.List[1].(*SSymbol)
+		if !ok {
+			return nil, fmt.Errorf("malformed qualified identifier '%s'", s)
+		}
+		sort, err := SexpToSort(s.List[2])
+		if err != nil {
+			return nil, err
+		}
+		return &As{Identifier(id.Symbol), sort}, nil
+	case IsSymbol(s.List[0], "-") && len(s.List) == 2:
+		// solvers print negative integers as (- N)
+		if i, ok := s.List[1].(*SInt); ok {
+			return &Int{-i.Int}, nil
+		}
+		if i, ok := s.List[1].(*SBigInt); ok {
+			return bigIntTerm(new(big.Int).Neg(i.Int)), nil
+		}
+	}
+
+	args := make([]Term, 0, len(s.List)-1)
+	for _, arg := range s.List[1:] {
+		t, err := SexpToTerm(arg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, t)
+	}
+
+	if head, ok := s.List[0].(*SList); ok && len(head.List) > 0 && IsSymbol(head.List[0], "_") {
+		return indexedToTerm(head, args)
+	}
+	id, ok := s.List[0].(*SSymbol)
+	if !ok {
+		return nil, fmt.Errorf("unparsable application '%s'", s)
+	}
+	return &App{Identifier(id.Symbol), args}, nil
+}
+
+func annotatedToTerm(s *SList) (Term, error) {
+	if len(s.List) < 3 {
+		return nil, fmt.Errorf("malformed annotation '%s'", s)
+	}
+	t, err := SexpToTerm(s.List[1])
+	if err != nil {
+		return nil, err
+	}
+	var attrs []Attribute
+	for rest := s.List[2:]; len(rest) > 0; {
+		kw, ok := rest[0].(*SKeyword)
+		if !ok {
+			return nil, fmt.Errorf("malformed annotation '%s'", s)
+		}
+		attr := Attribute{Keyword: kw.Keyword}
+		rest = rest[1:]
+		if len(rest) > 0 {
+			if _, ok := rest[0].(*SKeyword); !ok {
+				attr.Value = rest[0]
+				rest = rest[1:]
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	return &Annotated{t, attrs}, nil
+}
+
+// letToTerm converts a let to nested Lets, one per binding.  SMT-LIB
+// binds in parallel, so this is only possible when no binding refers
+// to a variable bound by an earlier binding in the same let.
+func letToTerm(s *SList) (Term, error) {
+	if len(s.List) != 3 {
+		return nil, fmt.Errorf("malformed let '%s'", s)
+	}
+	bindings, ok := s.List[1].(*SList)
+	if !ok || len(bindings.List) == 0 {
+		return nil, fmt.Errorf("malformed let bindings '%s'", s.List[1])
+	}
+	ids := make([]Identifier, 0, len(bindings.List))
+	values := make([]Term, 0, len(bindings.List))
+	for _, b := range bindings.List {
+		binding, ok := b.(*SList)
+		if !ok || len(binding.List) != 2 {
+			return nil, fmt.Errorf("malformed let binding '%s'", b)
+		}
+		id, ok := binding.List[0].(*SSymbol)
+		if !ok {
+			return nil, fmt.Errorf("malformed let binding '%s'", b)
+		}
+		value, err := SexpToTerm(binding.List[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, earlier := range ids {
+			if mentions(value, earlier) {
+				return nil, fmt.Errorf("unsupported parallel let: '%s' refers to %s", b, earlier)
+			}
+		}
+		ids = append(ids, Identifier(id.Symbol))
+		values = append(values, value)
+	}
+	t, err := SexpToTerm(s.List[2])
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		t = &Let{ids[i], values[i], t}
+	}
+	return t, nil
+}
+
+// mentions reports whether id occurs in t.
+func mentions(term Term, id Identifier) bool {
+	switch t := term.(type) {
+	case *Const:
+		return t.Id == id
+	case *App:
+		for _, arg := range t.Args {
+			if mentions(arg, id) {
+				return true
+			}
+		}
+	case *IndexedApp:
+		for _, arg := range t.Args {
+			if mentions(arg, id) {
+				return true
+			}
+		}
+	case *Let:
+		return mentions(t.Value, id) || mentions(t.In, id)
+	case *Annotated:
+		return mentions(t.Term, id)
+	case *Forall:
+		return mentions(t.Body, id)
+	case *Exists:
+		return mentions(t.Body, id)
+	}
+	return false
+}
+
+func sexpToSortedVars(sexp Sexp) ([]SortedVar, error) {
+	list, ok := sexp.(*SList)
+	if !ok || len(list.List) == 0 {
+		return nil, fmt.Errorf("malformed sorted variables '%s'", sexp)
+	}
+	vars := make([]SortedVar, 0, len(list.List))
+	for _, v := range list.List {
+		pair, ok := v.(*SList)
+		if !ok || len(pair.List) != 2 {
+			return nil, fmt.Errorf("malformed sorted variable '%s'", v)
+		}
+		id, ok := pair.List[0].(*SSymbol)
+		if !ok {
+			return nil, fmt.Errorf("malformed sorted variable '%s'", v)
+		}
+		sort, err := SexpToSort(pair.List[1])
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, SortedVar{Identifier(id.Symbol), sort})
+	}
+	return vars, nil
+}
+
+func sortedVarsToSexp(vars []SortedVar) Sexp {
+	list := make([]Sexp, 0, len(vars))
+	for _, v := range vars {
+		list = append(list, &SList{[]Sexp{IdToSexp(v.Id), SortToSexp(v.Sort)}})
+	}
+	return &SList{list}
+}
+
+func indexedToTerm(head *SList, args []Term) (Term, error) {
+	if len(head.List) < 3 {
+		return nil, fmt.Errorf("malformed indexed identifier '%s'", head)
+	}
+	id, ok := head.List[1].(*SSymbol)
+	if !ok {
+		return nil, fmt.Errorf("malformed indexed identifier '%s'", head)
+	}
+	indices := make([]int64, 0, len(head.List)-2)
+	for _, sexp := range head.List[2:] {
+		i, ok := sexp.(*SInt)
+		if !ok {
+			return nil, fmt.Errorf("unsupported index in '%s'", head)
+		}
+		indices = append(indices, i.Int)
+	}
+	return &IndexedApp{Identifier(id.Symbol), indices, args}, nil
+}
+
+func SexpToSort(sexp Sexp) (Sort, error) {
+	switch s := sexp.(type) {
+	case *SSymbol:
+		return &SortName{Identifier(s.Symbol)}, nil
+	case *SList:
+		if len(s.List) == 3 && IsSymbol(s.List[0], "_") && IsSymbol(s.List[1], "BitVec") {
+			if width, ok := s.List[2].(*SInt); ok {
+				return &BitVecSort{width.Int}, nil
+			}
+		}
+		if len(s.List) < 2 {
+			break
+		}
+		id, ok := s.List[0].(*SSymbol)
+		if !ok {
+			break
+		}
+		args := make([]Sort, 0, len(s.List)-1)
+		for _, arg := range s.List[1:] {
+			sort, err := SexpToSort(arg)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, sort)
+		}
+		return &SortApp{Identifier(id.Symbol), args}, nil
+	}
+	return nil, fmt.Errorf("unparsable sort '%s'", sexp)
+}
+
 func TermToSexp(term Term) Sexp {
 	switch t := term.(type) {
 	case *String:
 		return &SString{t.String}
 	case *Int:
+		if t.Int < 0 {
+			return negative(t.Int)
+		}
 		return &SInt{t.Int}
+	case *BigInt:
+		if t.Int.Sign() < 0 {
+			return &SList{[]Sexp{&SSymbol{"-"}, numeral(new(big.Int).Neg(t.Int))}}
+		}
+		return numeral(t.Int)
 	case *BitVec:
 		return &SBitVec{t.Value, t.Width}
 	case *Const:
@@ -327,6 +779,21 @@ func TermToSexp(term Term) Sexp {
 			args = append(args, TermToSexp(arg))
 		}
 		return &SList{args}
+	case *IndexedApp:
+		head := make([]Sexp, 0, len(t.Indices)+2)
+		head = append(head, &SSymbol{"_"}, IdToSexp(t.Id))
+		for _, i := range t.Indices {
+			head = append(head, &SInt{i})
+		}
+		if len(t.Args) == 0 {
+			return &SList{head}
+		}
+		args := make([]Sexp, 0, len(t.Args)+1)
+		args = append(args, &SList{head})
+		for _, arg := range t.Args {
+			args = append(args, TermToSexp(arg))
+		}
+		return &SList{args}
 	case *Let:
 		return &SList{[]Sexp{
 			&SSymbol{"let"},
@@ -335,10 +802,66 @@ func TermToSexp(term Term) Sexp {
 			}}}},
 			TermToSexp(t.In),
 		}}
+	case *As:
+		return &SList{[]Sexp{
+			&SSymbol{"as"},
+			IdToSexp(t.Id),
+			SortToSexp(t.Sort),
+		}}
+	case *Decimal:
+		return &SDecimal{t.Decimal}
+	case *Forall:
+		return &SList{[]Sexp{
+			&SSymbol{"forall"},
+			sortedVarsToSexp(t.Vars),
+			TermToSexp(t.Body),
+		}}
+	case *Exists:
+		return &SList{[]Sexp{
+			&SSymbol{"exists"},
+			sortedVarsToSexp(t.Vars),
+			TermToSexp(t.Body),
+		}}
+	case *Annotated:
+		list := []Sexp{&SSymbol{"!"}, TermToSexp(t.Term)}
+		return &SList{append(list, attributesToSexps(t.Attributes)...)}
 	}
 	panic("unreachable")
 }
 
+func attributesToSexps(attrs []Attribute) []Sexp {
+	var sexps []Sexp
+	for _, attr := range attrs {
+		sexps = append(sexps, &SKeyword{attr.Keyword})
+		if attr.Value != nil {
+			sexps = append(sexps, attr.Value)
+		}
+	}
+	return sexps
+}
+
+// numeral returns n, which isn't negative, as an SInt if it fits.
+func numeral(n *big.Int) Sexp {
+	if n.IsInt64() {
+		return &SInt{n.Int64()}
+	}
+	return &SBigInt{n}
+}
+
+// negative returns n, which is negative, as SMT-LIB writes it:
+// (- |n|).  |n| is an SBigInt for math.MinInt64.
+func negative(n int64) Sexp {
+	return &SList{[]Sexp{&SSymbol{"-"}, numeral(new(big.Int).Neg(big.NewInt(n)))}}
+}
+
+// bigIntTerm returns n as an Int if it fits, or else a BigInt.
+func bigIntTerm(n *big.Int) Term {
+	if n.IsInt64() {
+		return &Int{n.Int64()}
+	}
+	return &BigInt{n}
+}
+
 func IdToSexp(id Identifier) Sexp {
 	return &SSymbol{string(id)}
 }
@@ -380,21 +903,24 @@ func (*SSymbol) sexp()  {}
 func (*SString) sexp()  {}
 func (*SKeyword) sexp() {}
 func (*SInt) sexp()     {}
+func (*SBigInt) sexp()  {}
 func (*SBitVec) sexp()  {}
-
-func (s *SList) String() string {
-	r := "("
-	for i, child := range s.List {
-		r += child.String()
-		if i != len(s.List)-1 {
-			r += " "
-		}
-	}
-	r += ")\n"
-	return r
-}
-func (s *SSymbol) String() string  { return s.Symbol }
-func (s *SString) String() string  { return fmt.Sprintf(`"%s"`, s.Str) }
-func (s *SKeyword) String() string { return fmt.Sprintf(":%s", s.Keyword) }
-func (s *SInt) String() string     { return fmt.Sprintf("%v", s.Int) }
-func (s *SBitVec) String() string  { return fmt.Sprintf("(_ bv%d %d)", s.Value, s.Width) }
+func (*SDecimal) sexp() {}
+
+func (s *SList) String() string    { return sexpString(s) }
+func (s *SSymbol) String() string  { return sexpString(s) }
+func (s *SString) String() string  { return sexpString(s) }
+func (s *SKeyword) String() string { return sexpString(s) }
+func (s *SInt) String() string     { return sexpString(s) }
+func (s *SBigInt) String() string  { return sexpString(s) }
+func (s *SBitVec) String() string  { return sexpString(s) }
+func (s *SDecimal) String() string { return sexpString(s) }
+
+func (s *SList) WriteTo(w io.Writer) (int64, error)    { return writeSexp(w, s) }
+func (s *SSymbol) WriteTo(w io.Writer) (int64, error)  { return writeSexp(w, s) }
+func (s *SString) WriteTo(w io.Writer) (int64, error)  { return writeSexp(w, s) }
+func (s *SKeyword) WriteTo(w io.Writer) (int64, error) { return writeSexp(w, s) }
+func (s *SInt) WriteTo(w io.Writer) (int64, error)     { return writeSexp(w, s) }
+func (s *SBigInt) WriteTo(w io.Writer) (int64, error)  { return writeSexp(w, s) }
+func (s *SBitVec) WriteTo(w io.Writer) (int64, error)  { return writeSexp(w, s) }
+func (s *SDecimal) WriteTo(w io.Writer) (int64, error) { return writeSexp(w, s) }
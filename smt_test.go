@@ -0,0 +1,467 @@
+package smt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseTerm(t *testing.T, input string) Term {
+	sexp, err := NewParser(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Parse('%s'): %s", input, err)
+	}
+	term, err := SexpToTerm(sexp)
+	if err != nil {
+		t.Fatalf("SexpToTerm('%s'): %s", input, err)
+	}
+	return term
+}
+
+type seqTest struct {
+	input string
+	elems []Term
+}
+
+var seqData = []seqTest{
+	{"(as seq.empty (Seq Int))", nil},
+	{"(seq.unit 3)", []Term{&Int{3}}},
+	{"(seq.++ (seq.unit 1) (seq.unit (- 2)) (seq.unit 3))", []Term{&Int{1}, &Int{-2}, &Int{3}}},
+	{"(seq.++ (seq.unit a) (seq.++ (seq.unit b) (as seq.empty (Seq Int))))", []Term{&Const{"a"}, &Const{"b"}}},
+}
+
+func TestSeqToSlice(t *testing.T) {
+	for _, test := range seqData {
+		elems, err := SeqToSlice(parseTerm(t, test.input))
+		if err != nil {
+			t.Fatalf("SeqToSlice('%s'): %s", test.input, err)
+		}
+		if !reflect.DeepEqual(elems, test.elems) {
+			t.Fatalf("SeqToSlice('%s'): %#v != %#v", test.input, elems, test.elems)
+		}
+	}
+
+	if _, err := SeqToSlice(parseTerm(t, "(seq.len s)")); err == nil {
+		t.Fatalf("expected error decoding non-sequence value")
+	}
+}
+
+func TestSeqRT(t *testing.T) {
+	term := SeqConcat(SeqUnit(NewInt(-1)), SeqEmpty(IntSort))
+	expected := "(seq.++ (seq.unit (- 1)) (as seq.empty (Seq Int)))"
+	if s := TermToSexp(term).String(); s != expected {
+		t.Fatalf("TermToSexp: %s != %s", s, expected)
+	}
+	if rt := parseTerm(t, expected); !reflect.DeepEqual(rt, term) {
+		t.Fatalf("round trip: %#v != %#v", rt, term)
+	}
+}
+
+func TestSetToSlice(t *testing.T) {
+	input := "(set.union (set.singleton (tuple 1 2)) (set.insert (tuple 3 4) (as set.empty (Relation Int Int))))"
+	elems, err := SetToSlice(parseTerm(t, input))
+	if err != nil {
+		t.Fatalf("SetToSlice('%s'): %s", input, err)
+	}
+	expected := []Term{
+		Tuple(NewInt(1), NewInt(2)),
+		Tuple(NewInt(3), NewInt(4)),
+	}
+	if !reflect.DeepEqual(elems, expected) {
+		t.Fatalf("SetToSlice('%s'): %#v != %#v", input, elems, expected)
+	}
+}
+
+func TestTermTheories(t *testing.T) {
+	term := And(SetMember(NewConst("x"), NewConst("s")), Equals(SeqLen(NewConst("q")), NewInt(2)))
+	expected := []Theory{TheorySeq, TheorySets}
+	if theories := TermTheories(term); !reflect.DeepEqual(theories, expected) {
+		t.Fatalf("TermTheories: %v != %v", theories, expected)
+	}
+	if theories := SortTheories(RelationSort(IntSort, BoolSort)); !reflect.DeepEqual(theories, []Theory{TheorySets}) {
+		t.Fatalf("SortTheories: %v", theories)
+	}
+	if theories := TermTheories(Equals(NewConst("t"), Tuple(NewInt(1), NewInt(2)))); !reflect.DeepEqual(theories, []Theory{TheorySets}) {
+		t.Fatalf("TermTheories(tuple): %v", theories)
+	}
+	if theories := SortTheories(TupleSort(IntSort)); !reflect.DeepEqual(theories, []Theory{TheorySets}) {
+		t.Fatalf("SortTheories(Tuple): %v", theories)
+	}
+	if theories := TermTheories(Add(NewConst("a"), NewInt(1))); len(theories) != 0 {
+		t.Fatalf("TermTheories: unexpected %v", theories)
+	}
+}
+
+type builderTest struct {
+	term     Term
+	expected string
+}
+
+var builderData = []builderTest{
+	{And(), "true"},
+	{And(NewConst("a")), "a"},
+	{And(And(NewConst("a"), NewConst("b")), Or(NewConst("c"), NewConst("d")), And(NewConst("e"), NewConst("f"))), "(and a b (or c d) e f)"},
+	{Or(NewConst("a"), Or(NewConst("b"), NewConst("c"))), "(or a b c)"},
+	{Xor(Xor(NewConst("a"), NewConst("b")), NewConst("c")), "(xor a b c)"},
+	{Add(Add(NewConst("a"), NewInt(1)), Mul(NewConst("b"), NewConst("c")), NewInt(-2)), "(+ a 1 (* b c) (- 2))"},
+	{Mul(NewConst("a"), Mul(NewConst("b"), NewConst("c"))), "(* a b c)"},
+	{Sub(Sub(NewConst("a"), NewConst("b")), NewConst("c")), "(- a b c)"},
+	{Sub(Neg(NewConst("a")), NewConst("b")), "(- (- a) b)"},
+	{Neg(NewInt(3)), "(- 3)"},
+	{NewInt(math.MinInt64), "(- 9223372036854775808)"},
+	{Neg(NewInt(math.MinInt64)), "9223372036854775808"},
+	{Div(Abs(NewConst("a")), NewInt(2)), "(div (abs a) 2)"},
+	{Mod(NewConst("a"), NewInt(2)), "(mod a 2)"},
+	{Distinct(NewConst("a"), NewConst("b"), NewConst("c")), "(distinct a b c)"},
+	{Not(Divisible(3, NewConst("a"))), "(not ((_ divisible 3) a))"},
+}
+
+func TestBuilders(t *testing.T) {
+	for _, test := range builderData {
+		s := TermToSexp(test.term).String()
+		if s != test.expected {
+			t.Fatalf("TermToSexp: %s != %s", s, test.expected)
+		}
+		if rt := parseTerm(t, test.expected); !reflect.DeepEqual(rt, test.term) {
+			t.Fatalf("round trip '%s': %#v != %#v", test.expected, rt, test.term)
+		}
+	}
+}
+
+type onlyWriter struct {
+	w io.Writer
+}
+
+func (o onlyWriter) Write(b []byte) (int, error) { return o.w.Write(b) }
+
+func TestWriteTo(t *testing.T) {
+	sexp := TermToSexp(And(LT(NewConst("x"), NewInt(-3)), Equals(NewConst("s"), &String{"hi"}), Equals(NewConst("b"), NewBitVec(5, 8))))
+	expected := `(and (< x (- 3)) (= s "hi") (= b (_ bv5 8)))`
+
+	if s := sexp.String(); s != expected {
+		t.Fatalf("String: %s != %s", s, expected)
+	}
+
+	var buf bytes.Buffer
+	n, err := sexp.WriteTo(onlyWriter{&buf})
+	if err != nil {
+		t.Fatalf("WriteTo: %s", err)
+	}
+	if buf.String() != expected || n != int64(len(expected)) {
+		t.Fatalf("WriteTo: wrote %d (%s), expected %s", n, buf.String(), expected)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var unknown Capabilities
+	if !unknown.Supports(FeatureProofs) || !unknown.SupportsTheory(TheorySets) || !unknown.SupportsLogic("QF_LIA") {
+		t.Errorf("expected unknown capabilities to support everything")
+	}
+
+	caps := &Capabilities{
+		Name:     "bv",
+		Logics:   []string{"QF_BV"},
+		Theories: []Theory{TheorySeq},
+		Features: []Feature{FeatureUnsatCores},
+	}
+	if err := caps.CheckTheories([]Theory{TheorySeq}); err != nil {
+		t.Errorf("CheckTheories: %s", err)
+	}
+	if err := caps.CheckTheories([]Theory{TheorySeq, TheorySets}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("CheckTheories: expected ErrUnsupported, got %v", err)
+	}
+
+	cases := []struct {
+		cmd string
+		ok  bool
+	}{
+		{"(get-unsat-core)", true},
+		{"(get-proof)", false},
+		{"(check-sat-assuming (p))", false},
+		{"(set-logic QF_BV)", true},
+		{"(set-logic QF_LIA)", false},
+		{"(assert true)", true},
+		{"set-logic", true},
+	}
+	for _, c := range cases {
+		sexp, err := NewParser(strings.NewReader(c.cmd)).Next()
+		if err != nil {
+			t.Fatalf("parse %s: %s", c.cmd, err)
+		}
+		err = caps.CheckCommand(sexp)
+		if c.ok && err != nil {
+			t.Errorf("CheckCommand(%s): %s", c.cmd, err)
+		} else if !c.ok && !errors.Is(err, ErrUnsupported) {
+			t.Errorf("CheckCommand(%s): expected ErrUnsupported, got %v", c.cmd, err)
+		}
+	}
+}
+
+func TestOptionValues(t *testing.T) {
+	cases := []struct {
+		v    OptionValue
+		text string
+	}{
+		{BoolValue(true), "true"},
+		{BoolValue(false), "false"},
+		{IntValue(7), "7"},
+		{IntValue(-7), "(- 7)"},
+		{IntValue(math.MinInt64), "(- 9223372036854775808)"},
+		{DecimalValue("2.6"), "2.6"},
+		{StringValue(`say "hi"`), `"say ""hi"""`},
+		{SymbolValue("sat"), "sat"},
+		{KeywordValue("all"), ":all"},
+	}
+	for _, c := range cases {
+		sexp := OptionValueToSexp(c.v)
+		if sexp.String() != c.text {
+			t.Errorf("OptionValueToSexp(%#v): %s != %s", c.v, sexp, c.text)
+		}
+		v, err := SexpToOptionValue(sexp)
+		if err != nil || v != c.v {
+			t.Errorf("SexpToOptionValue(%s): %#v, %v", sexp, v, err)
+		}
+	}
+	if _, err := SexpToOptionValue(&SList{[]Sexp{&SSymbol{"a"}}}); err == nil {
+		t.Errorf("expected error for list value")
+	}
+}
+
+func TestStatistics(t *testing.T) {
+	sexp, err := NewParser(strings.NewReader(
+		`(:conflicts 12 :time 0.25 :solver "cdcl" :mode sat :hist (1 2))`)).Next()
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	stats, err := SexpToStatistics(sexp)
+	if err != nil {
+		t.Fatalf("SexpToStatistics: %s", err)
+	}
+	expected := Statistics{
+		"conflicts": int64(12),
+		"time":      0.25,
+		"solver":    "cdcl",
+		"mode":      "sat",
+		"hist":      "(1 2)",
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("SexpToStatistics: %#v", stats)
+	}
+	if f, ok := stats.Float("conflicts"); !ok || f != 12 {
+		t.Errorf("Float(conflicts): %v %v", f, ok)
+	}
+	if _, ok := stats.Int("time"); ok {
+		t.Errorf("Int(time): expected not a count")
+	}
+
+	for _, bad := range []string{"(:a)", "(a 1)", "3"} {
+		sexp, _ := NewParser(strings.NewReader(bad)).Next()
+		if _, err := SexpToStatistics(sexp); err == nil {
+			t.Errorf("SexpToStatistics(%s): expected error", bad)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	model := map[string]Term{
+		"x": NewInt(7),
+		"y": NewInt(-3),
+		"p": NewBool(true),
+		"r": &App{"/", []Term{&Decimal{"1.0"}, &Decimal{"2.0"}}},
+		"a": &BitVec{0xf0, 8},
+		"b": &BitVec{3, 8},
+		"z": &Const{"x"},
+	}
+	cases := []struct {
+		term     string
+		expected string
+	}{
+		{"(and p (> x y))", "true"},
+		{"(or false (not p))", "false"},
+		{"(=> p false)", "false"},
+		{"(xor p p true)", "true"},
+		{"(= x z 7)", "true"},
+		{"(distinct x y 7)", "false"},
+		{"(ite (< x 0) x y)", "(- 3)"},
+		{"(+ x y 1)", "5"},
+		{"(- x)", "(- 7)"},
+		{"(div y 2)", "(- 2)"},
+		{"(mod y 2)", "1"},
+		{"(div 7 (- 2))", "(- 3)"},
+		{"(abs y)", "3"},
+		{"(+ r 1)", "(/ 3.0 2.0)"},
+		{"(- r)", "(- (/ 1.0 2.0))"},
+		{"(* r 4.0)", "2.0"},
+		{"(/ x 2)", "(/ 7.0 2.0)"},
+		{"(to_int (- r))", "(- 1)"},
+		{"(is_int (* r 2))", "true"},
+		{"(<= 1 x x 8)", "true"},
+		{"((_ divisible 7) x)", "true"},
+		{"(let ((x 2)) (+ x z))", "9"},
+		{"(bvadd a a)", "(_ bv224 8)"},
+		{"(bvnot b)", "(_ bv252 8)"},
+		{"(bvneg b)", "(_ bv253 8)"},
+		{"(bvudiv a (_ bv0 8))", "(_ bv255 8)"},
+		{"(bvurem a (_ bv0 8))", "(_ bv240 8)"},
+		{"(bvsdiv a b)", "(_ bv251 8)"},
+		{"(bvsrem a b)", "(_ bv255 8)"},
+		{"(bvsmod a b)", "(_ bv2 8)"},
+		{"(bvashr a b)", "(_ bv254 8)"},
+		{"(bvlshr a b)", "(_ bv30 8)"},
+		{"(bvshl a (_ bv9 8))", "(_ bv0 8)"},
+		{"(bvslt a b)", "true"},
+		{"(bvult a b)", "false"},
+		{"(concat b a)", "(_ bv1008 16)"},
+		{"((_ extract 7 4) a)", "(_ bv15 4)"},
+		{"((_ sign_extend 8) a)", "(_ bv65520 16)"},
+		{"((_ zero_extend 8) a)", "(_ bv240 16)"},
+		{"((_ rotate_left 4) a)", "(_ bv15 8)"},
+		{"((_ rotate_right 1) b)", "(_ bv129 8)"},
+		{"((_ repeat 2) b)", "(_ bv771 16)"},
+		{"(bvcomp a a)", "(_ bv1 1)"},
+		{"(bv2nat a)", "240"},
+		{"((_ int2bv 4) y)", "(_ bv13 4)"},
+	}
+	for _, c := range cases {
+		v, err := Eval(parseTerm(t, c.term), model)
+		if err != nil {
+			t.Errorf("Eval(%s): %s", c.term, err)
+			continue
+		}
+		if s := TermToSexp(v).String(); s != c.expected {
+			t.Errorf("Eval(%s) = %s, expected %s", c.term, s, c.expected)
+		}
+	}
+
+	for _, bad := range []string{
+		"w",
+		"(f x)",
+		"(div x 0)",
+		"(+ x p)",
+		"(= a (_ bv1 4))",
+		"(forall ((w Int)) (> w x))",
+	} {
+		if v, err := Eval(parseTerm(t, bad), model); err == nil {
+			t.Errorf("Eval(%s) = %s, expected error", bad, TermToSexp(v))
+		}
+	}
+
+	if _, err := Eval(nil, model); err == nil {
+		t.Errorf("Eval(nil): expected error")
+	}
+	if _, err := Eval(NewConst("n"), map[string]Term{"n": nil}); err == nil {
+		t.Errorf("Eval with nil model value: expected error")
+	}
+	err := CheckModel(map[string]Term{"n": nil}, []Term{GT(NewConst("n"), NewInt(0))})
+	if verr, ok := err.(*ValidationError); !ok || len(verr.Failures) != 1 || verr.Failures[0].Err == nil {
+		t.Errorf("CheckModel with nil model value: %v", err)
+	}
+
+	// arguments that aren't needed aren't evaluated
+	if v, err := Eval(parseTerm(t, "(or p (f x))"), model); err != nil || !IsSymbol(TermToSexp(v), "true") {
+		t.Errorf("Eval(or): %v %v", v, err)
+	}
+}
+
+func TestAssertionStack(t *testing.T) {
+	var s AssertionStack
+	s.Assert(NewConst("p"))
+	s.Push()
+	s.Assert(NewConst("q"))
+	s.Track(&Push{2})
+	s.Track(&Assert{NewConst("r")})
+	if s.Level() != 3 || len(s.Terms()) != 3 || len(s.At(3)) != 1 {
+		t.Fatalf("unexpected stack: level %d, %v", s.Level(), s.Terms())
+	}
+	if err := s.Track(&Pop{4}); err == nil {
+		t.Errorf("expected error popping too many levels")
+	}
+	s.Track(&Pop{2})
+	if s.Level() != 1 || len(s.Terms()) != 2 {
+		t.Fatalf("unexpected stack after pop: level %d, %v", s.Level(), s.Terms())
+	}
+	s.Track(&ResetAssertions{})
+	if s.Level() != 0 || len(s.Terms()) != 0 {
+		t.Fatalf("unexpected stack after reset: level %d, %v", s.Level(), s.Terms())
+	}
+	if err := s.Pop(); err == nil {
+		t.Errorf("expected error popping empty stack")
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	s := &scriptSolver{asserted: [][]Term{nil}}
+	s.DeclareConst("x", &SortName{"Int"})
+	s.DeclareConst("y", &SortName{"Int"})
+	s.Assert(LT(NewConst("x"), NewConst("y")))
+	if err := ValidateModel(s); err != nil {
+		t.Fatalf("ValidateModel: %s", err)
+	}
+
+	// scriptSolver's models give x 0 and y 1, whatever is asserted
+	s.Push()
+	bad := GT(NewConst("x"), NewConst("y"))
+	s.Assert(bad)
+	s.Assert(NewApp("f", NewConst("x")))
+	err := ValidateModel(s)
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError, not %v", err)
+	}
+	if len(verr.Failures) != 2 || verr.Failures[0].Term != bad ||
+		!IsSymbol(TermToSexp(verr.Failures[0].Value), "false") || verr.Failures[1].Err == nil {
+		t.Fatalf("unexpected failures: %s", verr)
+	}
+
+	s.Pop()
+	if err := ValidateModel(s); err != nil {
+		t.Fatalf("ValidateModel after pop: %s", err)
+	}
+}
+
+func TestCheckPartialModel(t *testing.T) {
+	x, y := NewConst("x"), NewConst("y")
+	model := map[string]Term{"x": NewInt(1)}
+
+	// y isn't in the model, so its value doesn't matter
+	assertions := []Term{GT(x, NewInt(0)), GT(y, NewInt(0)), Implies(y, LT(x, NewInt(0)))}
+	if err := CheckModel(model, assertions); err != nil {
+		t.Fatalf("CheckModel: %s", err)
+	}
+	// but variables bound in the assertion aren't constants
+	bound := &Let{"y", NewInt(2), GT(x, y)}
+	err := CheckModel(model, []Term{bound})
+	if verr, ok := err.(*ValidationError); !ok || len(verr.Failures) != 1 {
+		t.Fatalf("CheckModel: expected %s to fail, got %v", TermToSexp(bound), err)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	x := NewConst("x")
+	named := Named(GT(x, NewInt(0)), "a1")
+	if s := TermToSexp(named).String(); s != "(! (> x 0) :named a1)" {
+		t.Errorf("Named: %s", s)
+	}
+	if rt := parseTerm(t, TermToSexp(named).String()); !reflect.DeepEqual(rt, named) {
+		t.Errorf("round trip: %#v", rt)
+	}
+	v, err := Eval(named, map[string]Term{"x": NewInt(1)})
+	if err != nil || !IsSymbol(TermToSexp(v), "true") {
+		t.Errorf("Eval(%s): %v %v", TermToSexp(named), v, err)
+	}
+	if th := TermTheories(Named(NewApp("seq.len", x), "a")); len(th) != 1 || th[0] != TheorySeq {
+		t.Errorf("TermTheories: %v", th)
+	}
+
+	tm := NewTermManager()
+	if a, b := tm.Intern(Named(x, "a")), tm.Intern(Named(x, "a")); a != b {
+		t.Errorf("Intern: named terms not shared")
+	}
+	if a, b := tm.Intern(Named(x, "a")), tm.Intern(Named(x, "b")); a == b {
+		t.Errorf("Intern: differently named terms shared")
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package smttest provides a fake smt.Solver for testing code that
+// uses one.
+package smttest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bpowers/go-smt"
+)
+
+// Solver is a scriptable, in-memory smt.Solver.  It records the
+// constants declared and terms asserted at each level of the
+// push/pop stack, and answers CheckSat and GetModel with whatever
+// the test has programmed.  A Solver is safe for concurrent use.
+type Solver struct {
+	// CheckSatFunc, if non-nil, is called by CheckSat with every
+	// current assertion.  Otherwise CheckSat returns the results
+	// queued with QueueCheckSat, in order, and then Sat.
+	CheckSatFunc func(assertions []smt.Term) (smt.Satisfiable, error)
+
+	// GetModelFunc, if non-nil, is called by GetModel.  Otherwise
+	// GetModel returns the models queued with QueueModel, in order,
+	// and then an empty model.
+	GetModelFunc func() (map[string]smt.Term, error)
+
+	// CommandFunc, if non-nil, answers Command.  Otherwise Command
+	// responds with success.
+	CommandFunc func(sexp smt.Sexp) (smt.Sexp, error)
+
+	// UnknownReason is returned by CheckSatLimited and
+	// ReasonUnknown as the reason for Unknown results.
+	UnknownReason string
+
+	// Stats is returned by Statistics.
+	Stats smt.Statistics
+
+	// Caps is returned by Capabilities, and is checked by
+	// DeclareConst, Assert and Command.  The zero value supports
+	// everything.
+	Caps smt.Capabilities
+
+	mu       sync.Mutex
+	logic    string
+	options  map[smt.Option]smt.OptionValue
+	info     map[smt.Info]smt.OptionValue
+	levels   []level
+	limits   []smt.Limits
+	results  []smt.Satisfiable
+	models   []map[string]smt.Term
+	commands []smt.Sexp
+	lastSat  smt.Satisfiable
+	checked  bool
+	closed   bool
+}
+
+type level struct {
+	consts     map[string]smt.Sort
+	assertions []smt.Term
+}
+
+var _ smt.Solver = &Solver{}
+
+func NewSolver() *Solver {
+	return &Solver{
+		options: make(map[smt.Option]smt.OptionValue),
+		info:    make(map[smt.Info]smt.OptionValue),
+		levels:  []level{{consts: make(map[string]smt.Sort)}},
+	}
+}
+
+// QueueCheckSat adds results to be returned by future calls to
+// CheckSat.
+func (s *Solver) QueueCheckSat(results ...smt.Satisfiable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, results...)
+}
+
+// QueueModel adds a model to be returned by a future call to
+// GetModel.
+func (s *Solver) QueueModel(model map[string]smt.Term) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = append(s.models, model)
+}
+
+func (s *Solver) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
+
+func (s *Solver) DeclareConst(id string, sort smt.Sort) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("smttest: solver closed")
+	}
+	if err := s.Caps.CheckTheories(smt.SortTheories(sort)); err != nil {
+		return err
+	}
+	if _, ok := s.lookup(id); ok {
+		return fmt.Errorf("smttest: %s already declared", id)
+	}
+	s.levels[len(s.levels)-1].consts[id] = sort
+	return nil
+}
+
+func (s *Solver) lookup(id string) (smt.Sort, bool) {
+	for _, l := range s.levels {
+		if sort, ok := l.consts[id]; ok {
+			return sort, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Solver) Assert(t smt.Term) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("smttest: solver closed")
+	}
+	if err := s.Caps.CheckTheories(smt.TermTheories(t)); err != nil {
+		return err
+	}
+	top := &s.levels[len(s.levels)-1]
+	top.assertions = append(top.assertions, t)
+	s.checked = false
+	return nil
+}
+
+func (s *Solver) CheckSat() (smt.Satisfiable, error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return smt.Unknown, fmt.Errorf("smttest: solver closed")
+	}
+	f := s.CheckSatFunc
+	assertions := s.assertions()
+	if f == nil {
+		result := smt.Sat
+		if len(s.results) > 0 {
+			result = s.results[0]
+			s.results = s.results[1:]
+		}
+		s.lastSat, s.checked = result, true
+		s.mu.Unlock()
+		return result, nil
+	}
+	s.mu.Unlock()
+
+	// call out without the lock held, in case f uses s
+	result, err := f(assertions)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSat, s.checked = result, err == nil
+	return result, err
+}
+
+// CheckSatLimited records limits, then answers as CheckSat does.
+func (s *Solver) CheckSatLimited(limits smt.Limits) (smt.Satisfiable, string, error) {
+	s.mu.Lock()
+	s.limits = append(s.limits, limits)
+	s.mu.Unlock()
+
+	result, err := s.CheckSat()
+	if err != nil || result != smt.Unknown {
+		return result, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return result, s.UnknownReason, nil
+}
+
+func (s *Solver) ReasonUnknown() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.checked || s.lastSat != smt.Unknown {
+		return ""
+	}
+	return s.UnknownReason
+}
+
+func (s *Solver) Statistics() (smt.Statistics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(smt.Statistics)
+	for name, v := range s.Stats {
+		stats[name] = v
+	}
+	return stats, nil
+}
+
+// Limits returns the limits passed to each call of CheckSatLimited.
+func (s *Solver) Limits() []smt.Limits {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smt.Limits(nil), s.limits...)
+}
+
+func (s *Solver) GetModel() (map[string]smt.Term, error) {
+	s.mu.Lock()
+	if !s.checked || s.lastSat != smt.Sat {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("smttest: model not available")
+	}
+	f := s.GetModelFunc
+	if f == nil {
+		defer s.mu.Unlock()
+		if len(s.models) == 0 {
+			return map[string]smt.Term{}, nil
+		}
+		model := s.models[0]
+		s.models = s.models[1:]
+		return model, nil
+	}
+	s.mu.Unlock()
+	return f()
+}
+
+func (s *Solver) Push() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.levels = append(s.levels, level{consts: make(map[string]smt.Sort)})
+	s.checked = false
+}
+
+func (s *Solver) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.levels) == 1 {
+		return fmt.Errorf("smttest: pop with empty stack")
+	}
+	s.levels = s.levels[:len(s.levels)-1]
+	s.checked = false
+	return nil
+}
+
+func (s *Solver) SetLogic(logic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.Caps.SupportsLogic(logic) {
+		return fmt.Errorf("smttest: logic %s: %w", logic, smt.ErrUnsupported)
+	}
+	s.logic = logic
+	return nil
+}
+
+func (s *Solver) SetOption(opt smt.Option, value smt.OptionValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options[opt] = value
+	return nil
+}
+
+// GetOption returns the value opt was last set to.
+func (s *Solver) GetOption(opt smt.Option) (smt.OptionValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.options[opt]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("smttest: option %s not set: %w", opt, smt.ErrUnsupported)
+}
+
+func (s *Solver) SetInfo(info smt.Info, value smt.OptionValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info[info] = value
+	return nil
+}
+
+// GetInfo returns the value info was last set to, or the name or
+// version from Caps.
+func (s *Solver) GetInfo(info smt.Info) (smt.Sexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.info[info]; ok {
+		return smt.OptionValueToSexp(v), nil
+	}
+	switch {
+	case info == smt.InfoName && s.Caps.Name != "":
+		return &smt.SString{s.Caps.Name}, nil
+	case info == smt.InfoVersion && s.Caps.Version != "":
+		return &smt.SString{s.Caps.Version}, nil
+	}
+	return nil, fmt.Errorf("smttest: info %s not set: %w", info, smt.ErrUnsupported)
+}
+
+// Logic returns the logic last set with SetLogic.
+func (s *Solver) Logic() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logic
+}
+
+func (s *Solver) Capabilities() smt.Capabilities {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Caps
+}
+
+func (s *Solver) Command(sexp smt.Sexp) (smt.Sexp, error) {
+	s.mu.Lock()
+	if err := s.Caps.CheckCommand(sexp); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.commands = append(s.commands, sexp)
+	f := s.CommandFunc
+	s.mu.Unlock()
+
+	if f == nil {
+		return &smt.SSymbol{"success"}, nil
+	}
+	return f(sexp)
+}
+
+// Level returns the number of pushes not yet popped.
+func (s *Solver) Level() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.levels) - 1
+}
+
+// Consts returns the sorts of every constant currently declared.
+func (s *Solver) Consts() map[string]smt.Sort {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	consts := make(map[string]smt.Sort)
+	for _, l := range s.levels {
+		for id, sort := range l.consts {
+			consts[id] = sort
+		}
+	}
+	return consts
+}
+
+// Assertions returns every current assertion, outermost level first.
+func (s *Solver) Assertions() []smt.Term {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.assertions()
+}
+
+func (s *Solver) assertions() []smt.Term {
+	var all []smt.Term
+	for _, l := range s.levels {
+		all = append(all, l.assertions...)
+	}
+	return all
+}
+
+// AssertionsAt returns the terms asserted at level n, where level 0
+// is the base level before any push.
+func (s *Solver) AssertionsAt(n int) []smt.Term {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n < 0 || n >= len(s.levels) {
+		return nil
+	}
+	return append([]smt.Term(nil), s.levels[n].assertions...)
+}
+
+// Commands returns every sexp passed to Command.
+func (s *Solver) Commands() []smt.Sexp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smt.Sexp(nil), s.commands...)
+}
+
+// Closed reports whether Close has been called.
+func (s *Solver) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// levelOf returns the level at which a term equal to t (printed
+// the same way) was asserted, or -1.
+func (s *Solver) levelOf(t smt.Term) int {
+	want := smt.TermToSexp(t).String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, l := range s.levels {
+		for _, a := range l.assertions {
+			if smt.TermToSexp(a).String() == want {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// ExpectAsserted fails the test unless t is currently asserted at
+// push level n (0 being the base level).
+func (s *Solver) ExpectAsserted(tb testing.TB, t smt.Term, n int) {
+	tb.Helper()
+	if l := s.levelOf(t); l != n {
+		if l < 0 {
+			tb.Errorf("smttest: %s not asserted, expected at level %d", smt.TermToSexp(t), n)
+		} else {
+			tb.Errorf("smttest: %s asserted at level %d, expected level %d", smt.TermToSexp(t), l, n)
+		}
+	}
+}
+
+// ExpectNotAsserted fails the test if t is currently asserted at
+// any level.
+func (s *Solver) ExpectNotAsserted(tb testing.TB, t smt.Term) {
+	tb.Helper()
+	if l := s.levelOf(t); l >= 0 {
+		tb.Errorf("smttest: %s unexpectedly asserted at level %d", smt.TermToSexp(t), l)
+	}
+}
+
+// ExpectDeclared fails the test unless id is currently declared
+// with the given sort.
+func (s *Solver) ExpectDeclared(tb testing.TB, id string, sort smt.Sort) {
+	tb.Helper()
+	s.mu.Lock()
+	got, ok := s.lookup(id)
+	s.mu.Unlock()
+	if !ok {
+		tb.Errorf("smttest: %s not declared", id)
+	} else if smt.SortToSexp(got).String() != smt.SortToSexp(sort).String() {
+		tb.Errorf("smttest: %s declared as %s, expected %s", id, smt.SortToSexp(got), smt.SortToSexp(sort))
+	}
+}
+
+// ExpectLevel fails the test unless the push/pop stack is n deep.
+func (s *Solver) ExpectLevel(tb testing.TB, n int) {
+	tb.Helper()
+	if l := s.Level(); l != n {
+		tb.Errorf("smttest: at level %d, expected %d", l, n)
+	}
+}
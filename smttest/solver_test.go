@@ -0,0 +1,151 @@
+package smttest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bpowers/go-smt"
+)
+
+func TestSolver(t *testing.T) {
+	s := NewSolver()
+	x := &smt.Const{"x"}
+	gt := smt.GT(x, &smt.Int{0})
+	lt := smt.LT(x, &smt.Int{10})
+
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if err := s.DeclareConst("x", smt.IntSort); err == nil {
+		t.Errorf("expected redeclaration to fail")
+	}
+	s.Assert(gt)
+	s.Push()
+	s.Assert(lt)
+
+	s.ExpectDeclared(t, "x", smt.IntSort)
+	s.ExpectAsserted(t, gt, 0)
+	s.ExpectAsserted(t, smt.LT(&smt.Const{"x"}, &smt.Int{10}), 1)
+	s.ExpectLevel(t, 1)
+
+	s.QueueCheckSat(smt.Unsat, smt.Sat)
+	s.QueueModel(map[string]smt.Term{"x": &smt.Int{5}})
+	if r, err := s.CheckSat(); err != nil || r != smt.Unsat {
+		t.Errorf("CheckSat: %v, %v", r, err)
+	}
+	if _, err := s.GetModel(); err == nil {
+		t.Errorf("expected GetModel after unsat to fail")
+	}
+	if r, err := s.CheckSat(); err != nil || r != smt.Sat {
+		t.Errorf("CheckSat: %v, %v", r, err)
+	}
+	model, err := s.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if v, ok := model["x"].(*smt.Int); !ok || v.Int != 5 {
+		t.Errorf("model: %v", model)
+	}
+
+	if err := s.Pop(); err != nil {
+		t.Fatalf("Pop: %s", err)
+	}
+	s.ExpectNotAsserted(t, lt)
+	s.ExpectLevel(t, 0)
+	if err := s.Pop(); err == nil {
+		t.Errorf("expected Pop at level 0 to fail")
+	}
+
+	s.CheckSatFunc = func(assertions []smt.Term) (smt.Satisfiable, error) {
+		if len(assertions) != 1 {
+			t.Errorf("CheckSatFunc: %d assertions", len(assertions))
+		}
+		return smt.Unknown, nil
+	}
+	if r, err := s.CheckSat(); err != nil || r != smt.Unknown {
+		t.Errorf("CheckSat: %v, %v", r, err)
+	}
+
+	r, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"get-info"}, &smt.SKeyword{"name"}}})
+	if err != nil || !smt.IsSymbol(r, "success") {
+		t.Errorf("Command: %s, %v", r, err)
+	}
+	if len(s.Commands()) != 1 {
+		t.Errorf("Commands: %v", s.Commands())
+	}
+	s.Close()
+	if !s.Closed() {
+		t.Errorf("expected Closed")
+	}
+}
+
+func TestSolverCapabilities(t *testing.T) {
+	s := NewSolver()
+	s.Caps = smt.Capabilities{Name: "fake", Theories: []smt.Theory{}, Features: []smt.Feature{}}
+
+	if err := s.DeclareConst("s", smt.SeqSort(smt.IntSort)); !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("DeclareConst: expected ErrUnsupported, got %v", err)
+	}
+	_, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"get-unsat-core"}}})
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("Command: expected ErrUnsupported, got %v", err)
+	}
+	if len(s.Commands()) != 0 {
+		t.Errorf("unsupported command recorded: %v", s.Commands())
+	}
+	if s.Capabilities().Name != "fake" {
+		t.Errorf("Capabilities: %#v", s.Capabilities())
+	}
+}
+
+func TestSolverOptions(t *testing.T) {
+	s := NewSolver()
+	s.Caps = smt.Capabilities{Name: "fake", Logics: []string{"QF_BV"}}
+
+	if err := s.SetLogic("QF_LIA"); !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("SetLogic: expected ErrUnsupported, got %v", err)
+	}
+	if err := s.SetLogic("QF_BV"); err != nil || s.Logic() != "QF_BV" {
+		t.Errorf("SetLogic: %v, %s", err, s.Logic())
+	}
+	s.SetOption(smt.OptionRandomSeed, smt.IntValue(3))
+	if v, err := s.GetOption(smt.OptionRandomSeed); err != nil || v != smt.IntValue(3) {
+		t.Errorf("GetOption: %v, %v", v, err)
+	}
+	if _, err := s.GetOption(smt.OptionVerbosity); err == nil {
+		t.Errorf("GetOption: expected error for unset option")
+	}
+	if v, err := s.GetInfo(smt.InfoName); err != nil || v.String() != `"fake"` {
+		t.Errorf("GetInfo: %v, %v", v, err)
+	}
+	s.SetInfo(smt.InfoStatus, smt.SymbolValue("unsat"))
+	if v, err := s.GetInfo(smt.InfoStatus); err != nil || v.String() != "unsat" {
+		t.Errorf("GetInfo: %v, %v", v, err)
+	}
+}
+
+func TestSolverUnknown(t *testing.T) {
+	s := NewSolver()
+	s.UnknownReason = "timeout"
+	s.Stats = smt.Statistics{"conflicts": int64(1)}
+
+	s.QueueCheckSat(smt.Unknown)
+	result, reason, err := s.CheckSatLimited(smt.Limits{ResourceLimit: 10})
+	if err != nil || result != smt.Unknown || reason != "timeout" {
+		t.Errorf("CheckSatLimited: %v, %q, %v", result, reason, err)
+	}
+	if s.ReasonUnknown() != "timeout" {
+		t.Errorf("ReasonUnknown: %q", s.ReasonUnknown())
+	}
+	if limits := s.Limits(); len(limits) != 1 || limits[0].ResourceLimit != 10 {
+		t.Errorf("Limits: %v", limits)
+	}
+	if stats, _ := s.Statistics(); stats["conflicts"] != int64(1) {
+		t.Errorf("Statistics: %v", stats)
+	}
+
+	s.CheckSat()
+	if s.ReasonUnknown() != "" {
+		t.Errorf("ReasonUnknown after sat: %q", s.ReasonUnknown())
+	}
+}
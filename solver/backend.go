@@ -0,0 +1,231 @@
+package solver
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/bpowers/go-smt"
+)
+
+// Backend describes a solver executable and how to talk SMT-LIB to
+// it interactively over stdin and stdout.
+type Backend struct {
+	Name string
+	// InfoName is the name the solver gives for (get-info :name).
+	InfoName string
+	// Exe lists the names the executable goes by, in the order
+	// they are looked for on PATH.
+	Exe  []string
+	Args []string
+	// Logics, Theories and Features list what the solver
+	// implements; each is nil if unknown, in which case the
+	// solver is trusted to support whatever is asked of it.
+	Logics   []string
+	Theories []smt.Theory
+	Features []smt.Feature
+	Quirks   Quirks
+}
+
+// Quirks records where a backend departs from what the rest of the
+// package otherwise assumes of a solver.
+type Quirks struct {
+	// Logic, if non-empty, is set with set-logic before the
+	// first command that needs a logic, like a declaration, if
+	// the caller hasn't set one; for solvers that won't accept
+	// declarations until a logic has been set.
+	Logic string
+
+	// TimeoutOption, ResourceOption and MemoryOption name the
+	// options that limit a query's time (in milliseconds),
+	// resource count and memory (in megabytes), each unlimited
+	// when set to 0.  Without a TimeoutOption, timeouts are only
+	// enforced by the watchdog; without the others, those limits
+	// are unsupported.
+	TimeoutOption  string
+	ResourceOption string
+	MemoryOption   string
+}
+
+// bvLogics are the logics of solvers specialized for bit-vectors.
+var bvLogics = []string{
+	"QF_BV", "QF_ABV", "QF_UFBV", "QF_AUFBV",
+	"BV", "ABV", "UFBV", "AUFBV",
+}
+
+var (
+	Z3 = &Backend{
+		Name:     "z3",
+		InfoName: "Z3",
+		Exe:      []string{"z3"},
+		Args:     []string{"-in", "-smt2"},
+		Theories: []smt.Theory{smt.TheorySeq},
+		Features: []smt.Feature{
+			smt.FeatureCheckSatAssuming,
+			smt.FeatureUnsatCores,
+			smt.FeatureProofs,
+		},
+		Quirks: Quirks{
+			TimeoutOption:  "timeout",
+			ResourceOption: "rlimit",
+			MemoryOption:   "memory_max_size",
+		},
+	}
+	CVC5 = &Backend{
+		Name:     "cvc5",
+		InfoName: "cvc5",
+		Exe:      []string{"cvc5"},
+		Args:     []string{"--incremental", "--lang", "smt2", "--produce-models"},
+		Theories: []smt.Theory{smt.TheorySeq, smt.TheorySets},
+		Features: []smt.Feature{
+			smt.FeatureCheckSatAssuming,
+			smt.FeatureUnsatCores,
+			smt.FeatureProofs,
+			smt.FeatureInterpolation,
+		},
+		Quirks: Quirks{
+			TimeoutOption:  "tlimit-per",
+			ResourceOption: "rlimit-per",
+		},
+	}
+	Yices2 = &Backend{
+		Name:     "yices2",
+		InfoName: "Yices",
+		Exe:      []string{"yices-smt2"},
+		Args:     []string{"--incremental"},
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{
+			smt.FeatureCheckSatAssuming,
+			smt.FeatureUnsatCores,
+		},
+		Quirks: Quirks{Logic: "ALL"},
+	}
+	Bitwuzla = &Backend{
+		Name:     "bitwuzla",
+		InfoName: "bitwuzla",
+		Exe:      []string{"bitwuzla"},
+		Args:     []string{"--lang", "smt2", "--produce-models"},
+		Logics: append([]string{
+			"QF_FP", "QF_BVFP", "QF_ABVFP", "QF_AUFBVFP", "ALL",
+		}, bvLogics...),
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{
+			smt.FeatureCheckSatAssuming,
+			smt.FeatureUnsatCores,
+		},
+		Quirks: Quirks{TimeoutOption: "time-limit-per"},
+	}
+	MathSAT = &Backend{
+		Name:     "mathsat",
+		InfoName: "MathSAT5",
+		Exe:      []string{"mathsat", "mathsat5"},
+		Args:     []string{"-input=smt2", "-model_generation=true"},
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{
+			smt.FeatureCheckSatAssuming,
+			smt.FeatureUnsatCores,
+			smt.FeatureInterpolation,
+		},
+	}
+	Boolector = &Backend{
+		Name:     "boolector",
+		InfoName: "Boolector",
+		Exe:      []string{"boolector"},
+		Args:     []string{"--smt2", "--incremental", "--model-gen"},
+		Logics:   bvLogics,
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{smt.FeatureCheckSatAssuming},
+	}
+)
+
+// Backends lists the solvers we know how to run.
+var Backends = []*Backend{Z3, CVC5, Yices2, Bitwuzla, MathSAT, Boolector}
+
+// backendFor returns the known backend whose executable is named
+// exe, or a backend with nothing known about it.
+func backendFor(exe string) *Backend {
+	for _, b := range Backends {
+		for _, name := range b.Exe {
+			if name == exe {
+				return b
+			}
+		}
+	}
+	return &Backend{Name: exe, Exe: []string{exe}}
+}
+
+// backendNamed returns the known backend that calls itself name in
+// response to (get-info :name), or nil.
+func backendNamed(name string) *Backend {
+	for _, b := range Backends {
+		if strings.EqualFold(b.InfoName, name) {
+			return b
+		}
+	}
+	return nil
+}
+
+// unknown reports whether nothing is known about b's solver.
+func (b *Backend) unknown() bool {
+	return b.Logics == nil && b.Theories == nil && b.Features == nil && b.Quirks == Quirks{}
+}
+
+func (b *Backend) capabilities() smt.Capabilities {
+	return smt.Capabilities{
+		Name:     b.Name,
+		Logics:   b.Logics,
+		Theories: b.Theories,
+		Features: b.Features,
+	}
+}
+
+// Path returns the location of the backend's executable on PATH.
+func (b *Backend) Path() (string, error) {
+	var err error
+	for _, exe := range b.Exe {
+		var path string
+		if path, err = exec.LookPath(exe); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s: %w", b.Name, err)
+}
+
+// Available reports whether the backend's executable is on PATH.
+func (b *Backend) Available() bool {
+	_, err := b.Path()
+	return err == nil
+}
+
+// New starts the backend's executable, found on PATH.
+func (b *Backend) New(opts *Options) (smt.Solver, error) {
+	path, err := b.Path()
+	if err != nil {
+		return nil, err
+	}
+	return startSolver(b, opts, path, b.Args...)
+}
+
+func NewZ3() (smt.Solver, error) {
+	return Z3.New(nil)
+}
+
+func NewCVC5() (smt.Solver, error) {
+	return CVC5.New(nil)
+}
+
+func NewYices2() (smt.Solver, error) {
+	return Yices2.New(nil)
+}
+
+func NewBitwuzla() (smt.Solver, error) {
+	return Bitwuzla.New(nil)
+}
+
+func NewMathSAT() (smt.Solver, error) {
+	return MathSAT.New(nil)
+}
+
+func NewBoolector() (smt.Solver, error) {
+	return Boolector.New(nil)
+}
@@ -0,0 +1,137 @@
+package solver
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/bpowers/go-smt"
+)
+
+func TestBackendFor(t *testing.T) {
+	if b := backendFor("yices-smt2"); b != Yices2 {
+		t.Errorf("backendFor(yices-smt2): %s", b.Name)
+	}
+	if b := backendFor("mathsat5"); b != MathSAT {
+		t.Errorf("backendFor(mathsat5): %s", b.Name)
+	}
+	b := backendFor("mysolver")
+	if b.Name != "mysolver" || b.Theories != nil {
+		t.Errorf("backendFor(mysolver): %#v", b)
+	}
+}
+
+func TestBackendMissing(t *testing.T) {
+	b := &Backend{Name: "missing", Exe: []string{"go-smt-no-such-solver"}}
+	if b.Available() {
+		t.Fatalf("expected backend to be unavailable")
+	}
+	_, err := b.New(nil)
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("New: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBackendQuirks(t *testing.T) {
+	var transcript bytes.Buffer
+	b := &Backend{
+		Name:     "fake",
+		Theories: []smt.Theory{},
+		Quirks:   Quirks{Logic: "QF_LIA"},
+	}
+	s, err := newFakeBackend(t, "sat", b, &Options{Transcript: &transcript})
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	defer s.Close()
+
+	if strings.Contains(transcript.String(), "set-logic") {
+		t.Errorf("expected logic to be left until needed:\n%s", transcript.String())
+	}
+	err = s.DeclareConst("s", smt.SeqSort(smt.IntSort))
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("DeclareConst: expected ErrUnsupported, got %v", err)
+	}
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if !strings.Contains(transcript.String(), "(set-logic QF_LIA)\n; success\n(declare-const x Int)") {
+		t.Errorf("expected logic to be set before declaring:\n%s", transcript.String())
+	}
+
+	// a logic set by the caller replaces the quirk's
+	s2, err := newFakeBackend(t, "sat", b, nil)
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	defer s2.Close()
+	if err := s2.SetLogic("QF_BV"); err != nil {
+		t.Fatalf("SetLogic: %s", err)
+	}
+	if err := s2.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst after SetLogic: %s", err)
+	}
+}
+
+func TestBackendCapabilities(t *testing.T) {
+	b := &Backend{
+		Name:     "fake",
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{smt.FeatureCheckSatAssuming},
+		Logics:   []string{"QF_BV"},
+	}
+	s, err := newFakeBackend(t, "sat", b, nil)
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	defer s.Close()
+
+	caps := s.Capabilities()
+	if !caps.Supports(smt.FeatureCheckSatAssuming) || caps.Supports(smt.FeatureUnsatCores) {
+		t.Errorf("Capabilities: %#v", caps)
+	}
+	_, err = s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"get-unsat-core"}}})
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("get-unsat-core: expected ErrUnsupported, got %v", err)
+	}
+	_, err = s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"set-logic"}, &smt.SSymbol{"QF_LIA"}}})
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("set-logic: expected ErrUnsupported, got %v", err)
+	}
+	r, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"set-logic"}, &smt.SSymbol{"QF_BV"}}})
+	if err != nil || !isSuccess(r) {
+		t.Errorf("set-logic: %v, %v", r, err)
+	}
+
+	// tuples belong to the sets theory, which b doesn't list
+	err = s.DeclareConst("t", smt.TupleSort(smt.IntSort, smt.IntSort))
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("DeclareConst Tuple: expected ErrUnsupported, got %v", err)
+	}
+	err = s.Assert(smt.Equals(smt.NewConst("u"), smt.Tuple(smt.NewInt(1), smt.NewInt(2))))
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("Assert tuple: expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestBackendNamed(t *testing.T) {
+	c := &cannedConn{responses: []string{
+		"success",
+		`(:name "Z3")`,
+		`(:version "4.12.2")`,
+	}}
+	s, err := newSolver(backendFor("/opt/solver"), c, nil)
+	if err != nil {
+		t.Fatalf("newSolver: %s", err)
+	}
+	caps := s.Capabilities()
+	if caps.Name != "z3" || caps.Version != "4.12.2" {
+		t.Errorf("Capabilities: %#v", caps)
+	}
+	err = s.DeclareConst("s", smt.SetSort(smt.IntSort))
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("DeclareConst: expected ErrUnsupported, got %v", err)
+	}
+}
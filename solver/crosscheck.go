@@ -0,0 +1,377 @@
+package solver
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bpowers/go-smt"
+)
+
+// CrossCheck is a solver that runs everything on two or more solvers,
+// to catch soundness bugs in them: check-sat fails with a
+// *DisagreementError if one says sat and another unsat, and
+// GetModel fails with a *ModelError if another solver finds the
+// first solver's model inconsistent with the assertions.  Either
+// error carries an SMT-LIB script reproducing the problem.
+//
+// Everything else is answered by the first solver.
+type CrossCheck struct {
+	mu      sync.Mutex
+	solvers []smt.Solver
+	script  []smt.Command
+	consts  []string // declared, for validating models
+	levels  []int    // len(consts) at each push
+	result  smt.Satisfiable
+	reason  string
+	broken  error // from a failed Push, leaving the solvers out of step
+}
+
+var _ smt.Solver = &CrossCheck{}
+
+func NewCrossCheck(solvers ...smt.Solver) (*CrossCheck, error) {
+	if len(solvers) < 2 {
+		return nil, fmt.Errorf("cross-checking needs at least 2 solvers, not %d", len(solvers))
+	}
+	return &CrossCheck{solvers: solvers, result: smt.Unknown}, nil
+}
+
+// DisagreementError reports solvers giving contradictory answers to
+// check-sat.
+type DisagreementError struct {
+	// Results holds each solver's name and answer.
+	Results []string
+	// Script reproduces the query.
+	Script string
+}
+
+func (e *DisagreementError) Error() string {
+	return fmt.Sprintf("solvers disagree: %s", strings.Join(e.Results, ", "))
+}
+
+// ModelError reports a model that another solver found to be
+// inconsistent with the assertions.
+type ModelError struct {
+	From, RejectedBy string
+	// Script asserts the model, and should be sat.
+	Script string
+}
+
+func (e *ModelError) Error() string {
+	return fmt.Sprintf("model from %s rejected by %s", e.From, e.RejectedBy)
+}
+
+func (c *CrossCheck) reproducer(extra ...smt.Command) string {
+	var buf bytes.Buffer
+	smt.WriteScript(&buf, append(append([]smt.Command(nil), c.script...), extra...))
+	return buf.String()
+}
+
+// each calls f on every solver, returning the first error.  Once a
+// Push has failed nothing is run, and its error is returned.
+func (c *CrossCheck) each(f func(s smt.Solver) error) error {
+	if c.broken != nil {
+		return c.broken
+	}
+	var firstErr error
+	for _, s := range c.solvers {
+		if err := f(s); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", s.Capabilities().Name, err)
+		}
+	}
+	return firstErr
+}
+
+// record adds cmd to the reproducer, and forgets the last check-sat.
+func (c *CrossCheck) record(cmd smt.Command) {
+	c.script = append(c.script, cmd)
+	c.result, c.reason = smt.Unknown, ""
+}
+
+func (c *CrossCheck) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.solvers {
+		s.Close()
+	}
+}
+
+func (c *CrossCheck) DeclareConst(id string, sort smt.Sort) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.DeclareConst{smt.Identifier(id), sort})
+	c.consts = append(c.consts, id)
+	return c.each(func(s smt.Solver) error {
+		return s.DeclareConst(id, sort)
+	})
+}
+
+func (c *CrossCheck) Assert(t smt.Term) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.Assert{t})
+	return c.each(func(s smt.Solver) error {
+		return s.Assert(t)
+	})
+}
+
+func (c *CrossCheck) CheckSat() (smt.Satisfiable, error) {
+	result, _, err := c.CheckSatLimited(smt.Limits{})
+	return result, err
+}
+
+// CheckSatLimited runs check-sat on every solver at once.  Solvers
+// that answer unknown don't disagree with anyone.
+func (c *CrossCheck) CheckSatLimited(limits smt.Limits) (smt.Satisfiable, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken != nil {
+		return smt.Unknown, "", c.broken
+	}
+	c.record(&smt.CheckSat{})
+
+	type answer struct {
+		result smt.Satisfiable
+		reason string
+		err    error
+	}
+	answers := make([]answer, len(c.solvers))
+	var wg sync.WaitGroup
+	for i, s := range c.solvers {
+		wg.Add(1)
+		go func(i int, s smt.Solver) {
+			defer wg.Done()
+			a := &answers[i]
+			a.result, a.reason, a.err = s.CheckSatLimited(limits)
+		}(i, s)
+	}
+	wg.Wait()
+
+	result := smt.Unknown
+	var results, reasons []string
+	disagree := false
+	for i, a := range answers {
+		name := c.solvers[i].Capabilities().Name
+		if a.err != nil {
+			return smt.Unknown, "", fmt.Errorf("%s: %w", name, a.err)
+		}
+		results = append(results, fmt.Sprintf("%s: %s", name, resultString(a.result)))
+		switch {
+		case a.result == smt.Unknown:
+			if a.reason != "" {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", name, a.reason))
+			}
+		case result == smt.Unknown:
+			result = a.result
+		case result != a.result:
+			disagree = true
+		}
+	}
+	if disagree {
+		return smt.Unknown, "", &DisagreementError{Results: results, Script: c.reproducer()}
+	}
+	c.result = result
+	if result == smt.Unknown {
+		sort.Strings(reasons)
+		c.reason = strings.Join(reasons, "; ")
+	}
+	return result, c.reason, nil
+}
+
+func resultString(result smt.Satisfiable) string {
+	switch result {
+	case smt.Sat:
+		return "sat"
+	case smt.Unsat:
+		return "unsat"
+	}
+	return "unknown"
+}
+
+func (c *CrossCheck) ReasonUnknown() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reason
+}
+
+func (c *CrossCheck) Statistics() (smt.Statistics, error) {
+	return c.solvers[0].Statistics()
+}
+
+// GetModel returns the first solver's model, after checking that
+// each of the other solvers finds the assertions satisfiable with
+// every declared constant set to its value in the model.
+func (c *CrossCheck) GetModel() (map[string]smt.Term, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken != nil {
+		return nil, c.broken
+	}
+	first := c.solvers[0]
+	model, err := first.GetModel()
+	if err != nil || c.result != smt.Sat {
+		return model, err
+	}
+
+	var pins []smt.Command
+	for _, id := range c.consts {
+		// a nil value is one that couldn't be parsed
+		if v, ok := model[id]; ok && v != nil {
+			pins = append(pins, &smt.Assert{smt.Equals(smt.NewConst(id), v)})
+		}
+	}
+	for _, s := range c.solvers[1:] {
+		result, err := validate(s, pins)
+		if err != nil {
+			return nil, fmt.Errorf("validating model with %s: %s", s.Capabilities().Name, err)
+		}
+		if result == smt.Unsat {
+			script := append([]smt.Command{&smt.Push{1}}, pins...)
+			script = append(script, &smt.CheckSat{}, &smt.Pop{1})
+			return nil, &ModelError{
+				From:       first.Capabilities().Name,
+				RejectedBy: s.Capabilities().Name,
+				Script:     c.reproducer(script...),
+			}
+		}
+	}
+	return model, nil
+}
+
+// validate checks the assertions pinned with pins in a new scope.
+func validate(s smt.Solver, pins []smt.Command) (smt.Satisfiable, error) {
+	if err := push(s); err != nil {
+		return smt.Unknown, err
+	}
+	defer s.Pop()
+	for _, pin := range pins {
+		if err := s.Assert(pin.(*smt.Assert).Term); err != nil {
+			return smt.Unknown, err
+		}
+	}
+	return s.CheckSat()
+}
+
+func (c *CrossCheck) Assertions() []smt.Term {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.solvers[0].Assertions()
+}
+
+// Push can't return errors, so if a solver fails to push, the
+// solvers are left at different levels and every later call fails.
+func (c *CrossCheck) Push() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.Push{1})
+	c.levels = append(c.levels, len(c.consts))
+	if err := c.each(push); err != nil && c.broken == nil {
+		c.broken = fmt.Errorf("push failed: %w", err)
+	}
+}
+
+func (c *CrossCheck) Pop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.Pop{1})
+	if len(c.levels) > 0 {
+		c.consts = c.consts[:c.levels[len(c.levels)-1]]
+		c.levels = c.levels[:len(c.levels)-1]
+	}
+	return c.each(func(s smt.Solver) error {
+		return s.Pop()
+	})
+}
+
+func (c *CrossCheck) SetLogic(logic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.SetLogic{logic})
+	return c.each(func(s smt.Solver) error {
+		return s.SetLogic(logic)
+	})
+}
+
+func (c *CrossCheck) SetOption(opt smt.Option, value smt.OptionValue) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.SetOption{string(opt), smt.OptionValueToSexp(value)})
+	return c.each(func(s smt.Solver) error {
+		return s.SetOption(opt, value)
+	})
+}
+
+func (c *CrossCheck) GetOption(opt smt.Option) (smt.OptionValue, error) {
+	return c.solvers[0].GetOption(opt)
+}
+
+func (c *CrossCheck) SetInfo(info smt.Info, value smt.OptionValue) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.record(&smt.SetInfo{string(info), smt.OptionValueToSexp(value)})
+	return c.each(func(s smt.Solver) error {
+		return s.SetInfo(info, value)
+	})
+}
+
+func (c *CrossCheck) GetInfo(info smt.Info) (smt.Sexp, error) {
+	return c.solvers[0].GetInfo(info)
+}
+
+// Capabilities are those every solver has.
+func (c *CrossCheck) Capabilities() smt.Capabilities {
+	caps := smt.Capabilities{Name: "crosscheck"}
+	for i, s := range c.solvers {
+		sc := s.Capabilities()
+		if i == 0 {
+			caps.Logics, caps.Theories, caps.Features = sc.Logics, sc.Theories, sc.Features
+			continue
+		}
+		caps.Logics = intersect(caps.Logics, sc.Logics)
+		caps.Theories = intersect(caps.Theories, sc.Theories)
+		caps.Features = intersect(caps.Features, sc.Features)
+	}
+	return caps
+}
+
+// intersect returns the elements of both a and b, where nil means
+// unknown, and so everything.
+func intersect[T comparable](a, b []T) []T {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	both := []T{}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				both = append(both, x)
+				break
+			}
+		}
+	}
+	return both
+}
+
+// Command sends sexp to every solver, returning the first solver's
+// response.
+func (c *CrossCheck) Command(sexp smt.Sexp) (smt.Sexp, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cmd, err := smt.SexpToCommand(sexp); err == nil {
+		c.record(cmd)
+	}
+	var first smt.Sexp
+	err := c.each(func(s smt.Solver) error {
+		r, err := s.Command(sexp)
+		if first == nil {
+			first = r
+		}
+		return err
+	})
+	return first, err
+}
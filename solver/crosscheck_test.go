@@ -0,0 +1,196 @@
+package solver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bpowers/go-smt"
+	"github.com/bpowers/go-smt/smttest"
+)
+
+// rejecting returns a fake that is unsat whenever a term printed as
+// bad is asserted.
+func rejecting(name, bad string) *smttest.Solver {
+	s := namedFake(name)
+	s.CheckSatFunc = func(assertions []smt.Term) (smt.Satisfiable, error) {
+		for _, a := range assertions {
+			if smt.TermToSexp(a).String() == bad {
+				return smt.Unsat, nil
+			}
+		}
+		return smt.Sat, nil
+	}
+	return s
+}
+
+func TestCrossCheck(t *testing.T) {
+	a, b := namedFake("a"), rejecting("b", "(= x 2)")
+	c, err := NewCrossCheck(a, b)
+	if err != nil {
+		t.Fatalf("NewCrossCheck: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	x := smt.GT(smt.NewConst("x"), smt.NewInt(0))
+	if err := c.Assert(x); err != nil {
+		t.Fatalf("Assert: %s", err)
+	}
+	a.ExpectAsserted(t, x, 0)
+	b.ExpectAsserted(t, x, 0)
+
+	result, err := c.CheckSat()
+	if err != nil || result != smt.Sat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+
+	// a model b agrees with
+	a.QueueModel(map[string]smt.Term{"x": smt.NewInt(1)})
+	model, err := c.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if model["x"].(*smt.Int).Int != 1 {
+		t.Errorf("GetModel: %v", model)
+	}
+	// validation happens in its own scope
+	b.ExpectLevel(t, 0)
+	b.ExpectNotAsserted(t, smt.Equals(smt.NewConst("x"), smt.NewInt(1)))
+
+	// and one it doesn't
+	c.CheckSat()
+	a.QueueModel(map[string]smt.Term{"x": smt.NewInt(2)})
+	_, err = c.GetModel()
+	var modelErr *ModelError
+	if !errors.As(err, &modelErr) {
+		t.Fatalf("GetModel: expected *ModelError, got %v", err)
+	}
+	if modelErr.From != "a" || modelErr.RejectedBy != "b" {
+		t.Errorf("ModelError: %s", modelErr)
+	}
+	expected := `(declare-const x Int)
+(assert (> x 0))
+(check-sat)
+(check-sat)
+(push 1)
+(assert (= x 2))
+(check-sat)
+(pop 1)
+`
+	if modelErr.Script != expected {
+		t.Errorf("reproducer:\n%s\n!=\n%s", modelErr.Script, expected)
+	}
+}
+
+func TestCrossCheckDisagreement(t *testing.T) {
+	a, b, u := namedFake("a"), namedFake("b"), namedFake("u")
+	a.QueueCheckSat(smt.Sat, smt.Sat)
+	b.QueueCheckSat(smt.Unsat, smt.Sat)
+	u.QueueCheckSat(smt.Unknown, smt.Unknown)
+	u.UnknownReason = "incomplete"
+
+	c, err := NewCrossCheck(a, b, u)
+	if err != nil {
+		t.Fatalf("NewCrossCheck: %s", err)
+	}
+	c.DeclareConst("p", smt.BoolSort)
+	c.Assert(smt.NewConst("p"))
+
+	_, err = c.CheckSat()
+	var disagreement *DisagreementError
+	if !errors.As(err, &disagreement) {
+		t.Fatalf("CheckSat: expected *DisagreementError, got %v", err)
+	}
+	if err.Error() != "solvers disagree: a: sat, b: unsat, u: unknown" {
+		t.Errorf("Error: %s", err)
+	}
+	if !strings.HasSuffix(disagreement.Script, "(assert p)\n(check-sat)\n") {
+		t.Errorf("reproducer:\n%s", disagreement.Script)
+	}
+
+	// unknown doesn't disagree with anything
+	result, err := c.CheckSat()
+	if err != nil || result != smt.Sat {
+		t.Errorf("CheckSat: %v, %v", result, err)
+	}
+}
+
+func TestCrossCheckSetup(t *testing.T) {
+	if _, err := NewCrossCheck(namedFake("a")); err == nil {
+		t.Errorf("expected NewCrossCheck to need 2 solvers")
+	}
+
+	a, b := namedFake("a"), namedFake("b")
+	a.Caps.Theories = []smt.Theory{smt.TheorySeq, smt.TheorySets}
+	b.Caps.Theories = []smt.Theory{smt.TheorySeq}
+	c, _ := NewCrossCheck(a, b)
+	caps := c.Capabilities()
+	if caps.SupportsTheory(smt.TheorySets) || !caps.SupportsTheory(smt.TheorySeq) {
+		t.Errorf("Capabilities: %#v", caps)
+	}
+
+	// an error from any solver is an error
+	if err := c.DeclareConst("s", smt.SetSort(smt.IntSort)); !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("DeclareConst: expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestCrossCheckUnparsedModel(t *testing.T) {
+	a, b := namedFake("a"), rejecting("b", "(= x 2)")
+	c, err := NewCrossCheck(a, b)
+	if err != nil {
+		t.Fatalf("NewCrossCheck: %s", err)
+	}
+	defer c.Close()
+
+	c.DeclareConst("x", smt.IntSort)
+	c.DeclareConst("arr", &smt.SortApp{"Array", []smt.Sort{smt.IntSort, smt.IntSort}})
+	if result, err := c.CheckSat(); err != nil || result != smt.Sat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	// values that couldn't be parsed aren't pinned
+	a.QueueModel(map[string]smt.Term{"x": smt.NewInt(1), "arr": nil})
+	if _, err := c.GetModel(); err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+}
+
+func TestCrossCheckPushFails(t *testing.T) {
+	a, broken := namedFake("a"), brokenPush{namedFake("broken")}
+	c, err := NewCrossCheck(a, broken)
+	if err != nil {
+		t.Fatalf("NewCrossCheck: %s", err)
+	}
+	defer c.Close()
+
+	c.DeclareConst("x", smt.IntSort)
+	if result, err := c.CheckSat(); err != nil || result != smt.Sat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	// validating the model pushes on broken
+	a.QueueModel(map[string]smt.Term{"x": smt.NewInt(1)})
+	if _, err := c.GetModel(); err == nil || !strings.Contains(err.Error(), "broken pipe") {
+		t.Errorf("GetModel: expected push error, got %v", err)
+	}
+
+	c.Push()
+	if err := c.Assert(smt.NewConst("p")); err == nil || !strings.Contains(err.Error(), "broken pipe") {
+		t.Errorf("Assert after failed Push: expected push error, got %v", err)
+	}
+	// the solvers are out of step, so everything after fails
+	if err := c.Assert(smt.NewConst("q")); err == nil || !strings.Contains(err.Error(), "broken pipe") {
+		t.Errorf("second Assert: expected push error, got %v", err)
+	}
+	if _, err := c.CheckSat(); err == nil {
+		t.Errorf("CheckSat after failed Push: expected error")
+	}
+	if _, err := c.GetModel(); err == nil {
+		t.Errorf("GetModel after failed Push: expected error")
+	}
+	if err := c.Pop(); err == nil {
+		t.Errorf("Pop after failed Push: expected error")
+	}
+}
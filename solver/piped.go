@@ -1,10 +1,15 @@
 package solver
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
 
 	"github.com/bpowers/go-smt"
 )
@@ -13,7 +18,33 @@ func isSuccess(sexp smt.Sexp) bool {
 	return smt.IsSymbol(sexp, "success")
 }
 
+// Options configures a piped solver.  A nil *Options is the same as
+// the zero Options.
+type Options struct {
+	// Transcript, if non-nil, receives a copy of every command sent
+	// to the solver, each followed by the solver's response as a
+	// comment.  The result is a valid .smt2 script, and can be
+	// replayed with NewReplaySolver.
+	Transcript io.Writer
+}
+
 func NewPipedSolver(exe string, args ...string) (smt.Solver, error) {
+	return NewPipedSolverWithOptions(nil, exe, args...)
+}
+
+// NewPipedSolverWithOptions runs exe with args as a solver.  If exe
+// is one of the known Backends, its quirks are taken into account,
+// but its Args are not added to args.
+//
+// Piped solvers are safe for concurrent use: each call waits for any
+// other in progress, so a call that hangs (see Limits) holds up the
+// rest, including Close.
+func NewPipedSolverWithOptions(opts *Options, exe string, args ...string) (smt.Solver, error) {
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+	return startSolver(backendFor(name), opts, exe, args...)
+}
+
+func startSolver(b *Backend, opts *Options, exe string, args ...string) (*solver, error) {
 	cmd := exec.Command(exe, args...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -28,14 +59,33 @@ func NewPipedSolver(exe string, args ...string) (smt.Solver, error) {
 		return nil, fmt.Errorf("Run: %s", err)
 	}
 
+	c := &pipeConn{
+		cmd:     cmd,
+		stdin:   stdin,
+		w:       bufio.NewWriter(stdin),
+		results: smt.NewParser(stdout),
+	}
+	return newSolver(b, c, opts)
+}
+
+// newSolver returns a solver talking over c, after turning on
+// print-success so that every command gets a response, and asking
+// the solver its name and version.  If nothing is known about b, but
+// the solver names itself as one of Backends, that backend is used
+// instead.
+func newSolver(b *Backend, c conn, opts *Options) (*solver, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Transcript != nil {
+		c = &transcriptConn{conn: c, w: opts.Transcript}
+	}
 	s := &solver{
-		cmd:          cmd,
-		stdin:        stdin,
-		stdoutCloser: stdout,
-		results:      smt.NewParser(stdout),
+		conn: c,
+		caps: smt.Capabilities{Name: b.Name},
 	}
 
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"set-option"},
 		&smt.SKeyword{"print-success"},
 		&smt.SSymbol{"true"}}})
@@ -48,42 +98,213 @@ func NewPipedSolver(exe string, args ...string) (smt.Solver, error) {
 		return nil, fmt.Errorf("print-success(%#v): %s", r, err)
 	}
 
+	name, err := s.getInfo("name")
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("get-info: %s", err)
+	}
+	if known := backendNamed(name); known != nil && b.unknown() {
+		b = known
+	}
+
+	version, err := s.getInfo("version")
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("get-info: %s", err)
+	}
+
+	s.backend = b
+	s.caps = b.capabilities()
+	s.caps.Version = version
+	if s.caps.Name == "" {
+		s.caps.Name = name
+	}
+
 	return s, nil
 }
 
-type solver struct {
-	cmd          *exec.Cmd
-	stdin        io.WriteCloser
-	stdoutCloser io.Closer
-	results      *smt.Parser
+// getInfo returns the solver's string response to (get-info
+// :keyword).  Not every solver implements every keyword, so an error
+// response just returns "".
+func (s *solver) getInfo(keyword string) (string, error) {
+	r, err := s.command(&smt.SList{[]smt.Sexp{
+		&smt.SSymbol{"get-info"},
+		&smt.SKeyword{keyword}}})
+	if err != nil {
+		return "", err
+	}
+	if info, ok := r.(*smt.SList); ok && len(info.List) == 2 {
+		if v, ok := info.List[1].(*smt.SString); ok {
+			return v.Str, nil
+		}
+	}
+	return "", nil
 }
 
-func (s *solver) Command(sexp smt.Sexp) (smt.Sexp, error) {
+// conn carries commands to a solver, and its responses back.
+type conn interface {
+	roundTrip(cmd smt.Sexp) (smt.Sexp, error)
+	// signal sends sig to the solver process, if there is one.
+	signal(sig os.Signal) error
+	close() error
+}
 
-	str := sexp.String()
-	n, err := s.stdin.Write([]byte(str))
+// pipeConn talks to a solver process over its stdin and stdout.
+type pipeConn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	w       *bufio.Writer // buffers writes to stdin
+	results *smt.Parser
+}
 
-	if n != len(str) {
-		return nil, fmt.Errorf("stdin.Write: short (%d < %d)", n, len(str))
+func (c *pipeConn) roundTrip(sexp smt.Sexp) (smt.Sexp, error) {
+	if _, err := sexp.WriteTo(c.w); err != nil {
+		return nil, fmt.Errorf("stdin.Write: %s", err)
 	}
-	if err != nil {
+	c.w.WriteByte('\n')
+	if err := c.w.Flush(); err != nil {
 		return nil, fmt.Errorf("stdin.Write: %s", err)
 	}
 
-	result, err := s.results.Read()
+	result, err := c.results.Next()
 	if err != nil {
-		return nil, fmt.Errorf("Parser.Read: %s", err)
+		return nil, fmt.Errorf("Parser.Next: %s", err)
 	}
 
 	return result, nil
 }
 
-func (s *solver) Close() {
+func (c *pipeConn) signal(sig os.Signal) error {
+	return c.cmd.Process.Signal(sig)
+}
+
+// close closes the solver's stdin, which solvers take as a request
+// to exit, and waits for it to do so.
+func (c *pipeConn) close() error {
+	c.stdin.Close()
+	return c.cmd.Wait()
+}
+
+type solver struct {
+	conn    conn
+	backend *Backend
+	caps    smt.Capabilities
+	// mu serializes calls, so that each command's response goes
+	// to its caller, and calls that send several commands aren't
+	// interleaved with others.
+	mu     sync.Mutex
+	limits smt.Limits // as last set on the solver
+	reason string     // for the last check-sat being unknown
+	// logicSet is set once a logic has been, by the caller or
+	// from the backend's Logic quirk.
+	logicSet bool
+	// asserted tracks what has been asserted, for Assertions.
+	asserted smt.AssertionStack
 
+	// checking is set while a check-sat is in progress, when
+	// it's safe to interrupt the solver.
+	checkMu  sync.Mutex
+	checking bool
+}
+
+func (s *solver) Capabilities() smt.Capabilities {
+	return s.caps
+}
+
+func (s *solver) Command(sexp smt.Sexp) (smt.Sexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.command(sexp)
+	if err != nil || !isSuccess(r) {
+		return r, err
+	}
+	// keep track of assertions made through the low-level
+	// interface too
+	if cmd, err := smt.SexpToCommand(sexp); err == nil {
+		s.asserted.Track(cmd)
+	}
+	return r, nil
+}
+
+func (s *solver) command(sexp smt.Sexp) (smt.Sexp, error) {
+	if err := s.caps.CheckCommand(sexp); err != nil {
+		return nil, err
+	}
+	name := commandName(sexp)
+	if err := s.defaultLogic(name); err != nil {
+		return nil, err
+	}
+	r, err := s.conn.roundTrip(sexp)
+	if err != nil || !isSuccess(r) {
+		return r, err
+	}
+	switch name {
+	case "set-logic":
+		s.logicSet = true
+	case "reset":
+		// options, the logic and our limits are back to their
+		// defaults, and print-success is off
+		s.logicSet, s.limits, s.reason = false, smt.Limits{}, ""
+		r, err = s.conn.roundTrip(smt.CommandToSexp(&smt.SetOption{"print-success", &smt.SSymbol{"true"}}))
+		if err == nil && !isSuccess(r) {
+			err = fmt.Errorf("print-success after reset: %s", r)
+		}
+	}
+	return r, err
+}
+
+// startCommands can be sent before a logic is set.
+var startCommands = map[string]bool{
+	"set-option": true,
+	"get-option": true,
+	"set-info":   true,
+	"get-info":   true,
+	"set-logic":  true,
+	"echo":       true,
+	"reset":      true,
+	"exit":       true,
+}
+
+// defaultLogic sets the backend's Logic quirk before the command
+// name, unless a logic has already been set or name doesn't need
+// one.  It's left until then so that callers can set their own.
+func (s *solver) defaultLogic(name string) error {
+	if s.backend == nil || s.backend.Quirks.Logic == "" || s.logicSet || startCommands[name] {
+		return nil
+	}
+	r, err := s.conn.roundTrip(smt.CommandToSexp(&smt.SetLogic{s.backend.Quirks.Logic}))
+	if err == nil && !isSuccess(r) {
+		err = fmt.Errorf("Command not success: %s", r)
+	}
+	if err != nil {
+		return fmt.Errorf("set-logic %s: %s", s.backend.Quirks.Logic, err)
+	}
+	s.logicSet = true
+	return nil
+}
+
+func commandName(sexp smt.Sexp) string {
+	if l, ok := sexp.(*smt.SList); ok && len(l.List) > 0 {
+		if sym, ok := l.List[0].(*smt.SSymbol); ok {
+			return sym.Symbol
+		}
+	}
+	return ""
+}
+
+func (s *solver) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn.close()
 }
 
 func (s *solver) DeclareConst(id string, sort smt.Sort) error {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.caps.CheckTheories(smt.SortTheories(sort)); err != nil {
+		return err
+	}
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"declare-const"},
 		&smt.SSymbol{id},
 		smt.SortToSexp(sort)}})
@@ -97,34 +318,159 @@ func (s *solver) DeclareConst(id string, sort smt.Sort) error {
 }
 
 func (s *solver) Assert(t smt.Term) error {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.caps.CheckTheories(smt.TermTheories(t)); err != nil {
+		return err
+	}
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"assert"},
-		smt.TermToSexp(t)}})
+		smt.TermToSexpShared(t)}})
 	if err != nil {
 		return fmt.Errorf("Command: %s", err)
 	}
 	if !isSuccess(r) {
 		return fmt.Errorf("Command not success: %s", r)
 	}
+	s.asserted.Assert(t)
 	return nil
 }
 
 func (s *solver) CheckSat() (smt.Satisfiable, error) {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	result, _, err := s.CheckSatLimited(smt.Limits{})
+	return result, err
+}
+
+func (s *solver) CheckSatLimited(limits smt.Limits) (smt.Satisfiable, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, reason, err := s.checkSat(limits)
+	s.reason = reason
+	return result, reason, err
+}
+
+func (s *solver) checkSat(limits smt.Limits) (smt.Satisfiable, string, error) {
+	if err := s.setLimits(limits); err != nil {
+		return smt.Unknown, "", err
+	}
+
+	var w *watchdog
+	if limits.Timeout > 0 {
+		w = startWatchdog(s.conn, limits.Timeout)
+	}
+	s.setChecking(true)
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"check-sat"}}})
+	s.setChecking(false)
+	if w.stop() {
+		if err != nil {
+			return smt.Unknown, "timeout", fmt.Errorf("killed after timeout: %s", err)
+		}
+		// the solver was interrupted, but survived; it may have
+		// found the answer before the interrupt took effect
+		if !smt.IsSymbol(r, "sat") && !smt.IsSymbol(r, "unsat") {
+			return smt.Unknown, "timeout", nil
+		}
+	}
 	if err != nil {
-		return smt.Unknown, fmt.Errorf("Command: %s", err)
+		return smt.Unknown, "", fmt.Errorf("Command: %s", err)
 	}
 	switch {
 	case smt.IsSymbol(r, "sat"):
-		return smt.Sat, nil
+		return smt.Sat, "", nil
 	case smt.IsSymbol(r, "unsat"):
-		return smt.Unsat, nil
+		return smt.Unsat, "", nil
 	case smt.IsSymbol(r, "unknown"):
-		return smt.Unknown, nil
+		return smt.Unknown, s.reasonUnknown(), nil
 	default:
-		return smt.Unknown, fmt.Errorf("unexpected (check-sat): %s", r)
+		return smt.Unknown, "", fmt.Errorf("unexpected (check-sat): %s", r)
+	}
+}
+
+func (s *solver) setChecking(checking bool) {
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	s.checking = checking
+}
+
+// Interrupt asks the solver to abandon the check-sat in progress, if
+// any, which most solvers then answer with unknown.
+func (s *solver) Interrupt() (bool, error) {
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+	if !s.checking {
+		return false, nil
+	}
+	return true, s.conn.signal(os.Interrupt)
+}
+
+func (s *solver) ReasonUnknown() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason
+}
+
+func (s *solver) Statistics() (smt.Statistics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.info(smt.InfoAllStatistics)
+	if err != nil {
+		return nil, err
+	}
+	return smt.SexpToStatistics(r)
+}
+
+// reasonUnknown asks the solver why its last check-sat was unknown,
+// returning "" if it won't say.
+func (s *solver) reasonUnknown() string {
+	r, err := s.info(smt.InfoReasonUnknown)
+	if err != nil {
+		return ""
 	}
+	switch r := r.(type) {
+	case *smt.SSymbol:
+		return r.Symbol
+	case *smt.SString:
+		return r.Str
+	}
+	return r.String()
+}
+
+// setLimits sets the backend's options for whichever limits differ
+// from those last set.
+func (s *solver) setLimits(limits smt.Limits) error {
+	timeout := limits.Timeout.Milliseconds()
+	if limits.Timeout > 0 && timeout == 0 {
+		timeout = 1
+	}
+	quirks := &s.backend.Quirks
+	options := []struct {
+		name     string
+		option   string
+		old, new int64
+	}{
+		{"timeout", quirks.TimeoutOption, s.limits.Timeout.Milliseconds(), timeout},
+		{"resource limit", quirks.ResourceOption, s.limits.ResourceLimit, limits.ResourceLimit},
+		{"memory limit", quirks.MemoryOption, s.limits.MemoryMB, limits.MemoryMB},
+	}
+	for _, o := range options {
+		if o.old == o.new {
+			continue
+		}
+		if o.option == "" {
+			// the watchdog handles timeouts
+			if o.name != "timeout" && o.new != 0 {
+				return fmt.Errorf("%s doesn't implement a %s: %w", s.caps.Name, o.name, smt.ErrUnsupported)
+			}
+			continue
+		}
+		err := s.setOption(smt.Option(o.option), smt.IntValue(o.new))
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", o.name, err)
+		}
+	}
+	s.limits = limits
+	return nil
 }
 
 func readModel(sexps []smt.Sexp) (map[string]smt.Term, error) {
@@ -152,12 +498,10 @@ func readModel(sexps []smt.Sexp) (map[string]smt.Term, error) {
 				continue
 			}
 
-			if !smt.IsSymbol(app.List[3], "Int") {
-				log.Printf("readModel: expected Int sort, not %s", app.List[3])
-			}
 			t, err := smt.SexpToTerm(app.List[4])
 			if err != nil {
-				log.Printf("couldn't parse sexp: %s", err)
+				log.Printf("readModel: couldn't parse value of %s: %s", name.Symbol, err)
+				continue
 			}
 			terms[name.Symbol] = t
 		default:
@@ -170,7 +514,9 @@ func readModel(sexps []smt.Sexp) (map[string]smt.Term, error) {
 }
 
 func (s *solver) GetModel() (map[string]smt.Term, error) {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"get-model"}}})
 	if err != nil {
 		return nil, fmt.Errorf("Command: %s", err)
@@ -178,20 +524,115 @@ func (s *solver) GetModel() (map[string]smt.Term, error) {
 
 	switch app := r.(type) {
 	case *smt.SList:
-		if len(app.List) == 0 {
-			return nil, fmt.Errorf("expected model, got empty slist")
+		// older solvers start the model with the symbol model,
+		// newer ones just list the definitions
+		if len(app.List) > 0 && smt.IsSymbol(app.List[0], "model") {
+			return readModel(app.List[1:])
 		}
-		if !smt.IsSymbol(app.List[0], "model") {
-			return nil, fmt.Errorf("expected model, got2 %s", app)
-		}
-		return readModel(app.List[1:])
+		return readModel(app.List)
 	default:
 		return nil, fmt.Errorf("expected model, got %s", r)
 	}
 }
 
+// run sends cmd, which should succeed.
+func (s *solver) run(cmd smt.Sexp) error {
+	r, err := s.command(cmd)
+	if err != nil {
+		return fmt.Errorf("Command: %w", err)
+	}
+	if smt.IsSymbol(r, "unsupported") {
+		return fmt.Errorf("%s: %w", cmd, smt.ErrUnsupported)
+	}
+	if !isSuccess(r) {
+		return fmt.Errorf("Command not success: %s", r)
+	}
+	return nil
+}
+
+// query sends cmd, returning its response unless that is an error.
+func (s *solver) query(cmd smt.Sexp) (smt.Sexp, error) {
+	r, err := s.command(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("Command: %w", err)
+	}
+	if smt.IsSymbol(r, "unsupported") {
+		return nil, fmt.Errorf("%s: %w", cmd, smt.ErrUnsupported)
+	}
+	if list, ok := r.(*smt.SList); ok && len(list.List) > 0 && smt.IsSymbol(list.List[0], "error") {
+		return nil, fmt.Errorf("%s: %s", cmd, r)
+	}
+	return r, nil
+}
+
+func (s *solver) SetLogic(logic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(smt.CommandToSexp(&smt.SetLogic{logic}))
+}
+
+func (s *solver) SetOption(opt smt.Option, value smt.OptionValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setOption(opt, value)
+}
+
+func (s *solver) setOption(opt smt.Option, value smt.OptionValue) error {
+	// we rely on print-success to know when each command is done
+	if opt == smt.OptionPrintSuccess {
+		return fmt.Errorf("print-success can't be changed on a piped solver")
+	}
+	return s.run(smt.CommandToSexp(&smt.SetOption{string(opt), smt.OptionValueToSexp(value)}))
+}
+
+func (s *solver) GetOption(opt smt.Option) (smt.OptionValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.query(smt.CommandToSexp(&smt.GetOption{string(opt)}))
+	if err != nil {
+		return nil, err
+	}
+	return smt.SexpToOptionValue(r)
+}
+
+func (s *solver) SetInfo(info smt.Info, value smt.OptionValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(smt.CommandToSexp(&smt.SetInfo{string(info), smt.OptionValueToSexp(value)}))
+}
+
+func (s *solver) GetInfo(info smt.Info) (smt.Sexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info(info)
+}
+
+func (s *solver) info(info smt.Info) (smt.Sexp, error) {
+	r, err := s.query(smt.CommandToSexp(&smt.GetInfo{string(info)}))
+	if err != nil {
+		return nil, err
+	}
+	if list, ok := r.(*smt.SList); ok && len(list.List) == 2 {
+		if kw, ok := list.List[0].(*smt.SKeyword); ok && kw.Keyword == string(info) {
+			return list.List[1], nil
+		}
+	}
+	// z3 answers :all-statistics with the statistics themselves
+	if list, ok := r.(*smt.SList); ok && info == smt.InfoAllStatistics {
+		if len(list.List) == 0 {
+			return list, nil
+		}
+		if _, ok := list.List[0].(*smt.SKeyword); ok {
+			return list, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected (get-info :%s): %s", info, r)
+}
+
 func (s *solver) Push() {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"push"}}})
 	if err != nil {
 		panic(fmt.Sprintf("Command: %s", err))
@@ -199,10 +640,13 @@ func (s *solver) Push() {
 	if !isSuccess(r) {
 		panic(fmt.Sprintf("Command not success: %s", r))
 	}
+	s.asserted.Push()
 }
 
 func (s *solver) Pop() error {
-	r, err := s.Command(&smt.SList{[]smt.Sexp{
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, err := s.command(&smt.SList{[]smt.Sexp{
 		&smt.SSymbol{"pop"}}})
 	if err != nil {
 		return fmt.Errorf("Command: %s", err)
@@ -210,5 +654,11 @@ func (s *solver) Pop() error {
 	if !isSuccess(r) {
 		return fmt.Errorf("Command not success: %s", r)
 	}
-	return nil
+	return s.asserted.Pop()
+}
+
+func (s *solver) Assertions() []smt.Term {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.asserted.Terms()
 }
@@ -0,0 +1,599 @@
+package solver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bpowers/go-smt"
+)
+
+// fakeSolverEnv, when set in the environment of the test binary,
+// makes TestHelperProcess act as an SMT-LIB solver instead of
+// running tests.  Its value picks the solver's behavior; see
+// fakeSolver.
+const fakeSolverEnv = "GO_SMT_FAKE_SOLVER"
+
+// newFakeSolver starts the test binary as a piped solver behaving as
+// mode says.
+func newFakeSolver(t *testing.T, mode string) (smt.Solver, error) {
+	return newFakeBackend(t, mode, &Backend{Name: "fake"}, nil)
+}
+
+func newFakeBackend(t *testing.T, mode string, b *Backend, opts *Options) (smt.Solver, error) {
+	t.Setenv(fakeSolverEnv, mode)
+	return startSolver(b, opts, os.Args[0], "-test.run=^TestHelperProcess$")
+}
+
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv(fakeSolverEnv)
+	if mode == "" {
+		return
+	}
+	if err := fakeSolver(os.Stdin, os.Stdout, mode); err != nil {
+		fmt.Fprintf(os.Stderr, "fake solver: %s\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// fakeSolver reads commands from r and writes responses to w, just
+// well enough to stand in for a real solver.  Every check-sat is
+// answered with mode, except that:
+//
+//	crash:            the process exits instead
+//	hang:             check-sat waits to be interrupted, then is unknown
+//	hang-sat:         check-sat waits to be interrupted, then is sat
+//	hang-hard:        check-sat ignores interrupts, and never returns
+//	no-print-success: print-success is refused
+//
+// get-model returns 0 for every Int, false for every Bool and a
+// constant array of 0s for every array constant, and leaves out
+// constants whose names start with "elim", though get-value gives
+// them too.  get-value only works on constants.
+func fakeSolver(r io.Reader, w io.Writer, mode string) error {
+	out := bufio.NewWriter(w)
+	respond := func(s string) error {
+		out.WriteString(s)
+		out.WriteByte('\n')
+		return out.Flush()
+	}
+
+	printSuccess := false
+	logicSet := false
+	options := make(map[string]string)
+	reasonUnknown := "incomplete"
+
+	// catch interrupts from the start, so that one arriving just
+	// before check-sat doesn't kill the process
+	interrupt := make(chan os.Signal, 1)
+	if mode == "hang" || mode == "hang-sat" {
+		signal.Notify(interrupt, os.Interrupt)
+	}
+	var names []string
+	sorts := make(map[string]string)
+	levels := []int{0} // len(names) at each push
+	value := func(id string) string {
+		switch {
+		case sorts[id] == "Bool":
+			return "false"
+		case strings.HasPrefix(sorts[id], "(Array"):
+			return fmt.Sprintf("((as const %s) 0)", sorts[id])
+		}
+		return "0"
+	}
+
+	p := smt.NewParser(r)
+	for {
+		sexp, err := p.Next()
+		if err == smt.ParserEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		cmd, ok := sexp.(*smt.SList)
+		if !ok || len(cmd.List) == 0 {
+			respond(`(error "expected command")`)
+			continue
+		}
+		name, _ := cmd.List[0].(*smt.SSymbol)
+		if name == nil {
+			respond(`(error "expected command")`)
+			continue
+		}
+
+		var result string
+		switch name.Symbol {
+		case "set-option":
+			if len(cmd.List) == 3 && cmd.List[1].String() == ":print-success" {
+				if mode == "no-print-success" {
+					result = "unsupported"
+					break
+				}
+				printSuccess = smt.IsSymbol(cmd.List[2], "true")
+			} else if len(cmd.List) == 3 {
+				options[cmd.List[1].String()] = cmd.List[2].String()
+			}
+		case "get-option":
+			if v, ok := options[cmd.List[1].String()]; ok {
+				result = v
+			} else {
+				result = "unsupported"
+			}
+		case "set-info":
+		case "declare-const":
+			id := cmd.List[1].String()
+			if _, ok := sorts[id]; ok {
+				result = fmt.Sprintf(`(error "constant %s already declared")`, id)
+				break
+			}
+			names = append(names, id)
+			sorts[id] = cmd.List[2].String()
+		case "assert":
+			if len(cmd.List) != 2 {
+				result = `(error "assert takes one term")`
+			}
+		case "push":
+			levels = append(levels, len(names))
+		case "pop":
+			if len(levels) == 1 {
+				result = `(error "pop with empty stack")`
+				break
+			}
+			n := levels[len(levels)-1]
+			for _, id := range names[n:] {
+				delete(sorts, id)
+			}
+			names = names[:n]
+			levels = levels[:len(levels)-1]
+		case "echo":
+			result = cmd.List[1].String()
+		case "reset-assertions":
+			names, sorts, levels = nil, make(map[string]string), []int{0}
+		case "reset":
+			names, sorts, levels = nil, make(map[string]string), []int{0}
+			options, logicSet = make(map[string]string), false
+			// like real solvers, answer the reset before
+			// print-success goes back to off
+			if printSuccess {
+				result = "success"
+			}
+			printSuccess = false
+		case "check-sat":
+			switch mode {
+			case "crash":
+				return fmt.Errorf("crashing on check-sat")
+			case "hang":
+				<-interrupt
+				result, reasonUnknown = "unknown", "canceled"
+			case "hang-sat":
+				<-interrupt
+				result = "sat"
+			case "hang-hard":
+				signal.Ignore(os.Interrupt)
+				time.Sleep(time.Hour)
+			default:
+				result = mode
+			}
+		case "get-model":
+			model := []string{"(model"}
+			for _, id := range names {
+				if strings.HasPrefix(id, "elim") {
+					continue
+				}
+				model = append(model, fmt.Sprintf("  (define-fun %s () %s %s)", id, sorts[id], value(id)))
+			}
+			result = strings.Join(model, "\n") + ")"
+		case "get-value":
+			var values []string
+			for _, t := range cmd.List[1].(*smt.SList).List {
+				values = append(values, fmt.Sprintf("(%s %s)", t, value(t.String())))
+			}
+			result = "(" + strings.Join(values, " ") + ")"
+		case "set-logic":
+			if logicSet {
+				result = `(error "logic already set")`
+			}
+			logicSet = true
+		case "get-info":
+			switch cmd.List[len(cmd.List)-1].String() {
+			case ":name":
+				result = `(:name "fake")`
+			case ":version":
+				result = `(:version "1.0-fake")`
+			case ":all-statistics":
+				result = "(:conflicts 3 :time 0.01 :memory 19.50)"
+			case ":reason-unknown":
+				result = fmt.Sprintf("(:reason-unknown %s)", reasonUnknown)
+			default:
+				result = "unsupported"
+			}
+		case "exit":
+			return respond("success")
+		default:
+			result = fmt.Sprintf(`(error "unsupported command %s")`, name.Symbol)
+		}
+
+		if result == "" {
+			if !printSuccess {
+				continue
+			}
+			result = "success"
+		}
+		if err := respond(result); err != nil {
+			return err
+		}
+	}
+}
+
+func TestPipedSolver(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	if caps := s.Capabilities(); caps.Name != "fake" || caps.Version != "1.0-fake" {
+		t.Errorf("Capabilities: %#v", caps)
+	}
+
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if err := s.DeclareConst("p", smt.BoolSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if err := s.Assert(smt.GT(smt.NewConst("x"), smt.NewInt(-1))); err != nil {
+		t.Fatalf("Assert: %s", err)
+	}
+	s.Push()
+	if err := s.DeclareConst("y", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	result, err := s.CheckSat()
+	if err != nil {
+		t.Fatalf("CheckSat: %s", err)
+	}
+	if result != smt.Sat {
+		t.Errorf("CheckSat: expected sat, got %v", result)
+	}
+	model, err := s.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if len(model) != 3 {
+		t.Errorf("GetModel: expected 3 constants, got %v", model)
+	}
+	if v, ok := model["x"].(*smt.Int); !ok || v.Int != 0 {
+		t.Errorf("GetModel: x = %v", model["x"])
+	}
+	if v, ok := model["p"].(*smt.Const); !ok || v.Id != "false" {
+		t.Errorf("GetModel: p = %v", model["p"])
+	}
+
+	if err := s.Pop(); err != nil {
+		t.Fatalf("Pop: %s", err)
+	}
+	model, err = s.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if _, ok := model["y"]; ok {
+		t.Errorf("GetModel: y still declared after pop")
+	}
+}
+
+func TestPipedSolverUnsat(t *testing.T) {
+	s, err := newFakeSolver(t, "unsat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	result, err := s.CheckSat()
+	if err != nil {
+		t.Fatalf("CheckSat: %s", err)
+	}
+	if result != smt.Unsat {
+		t.Errorf("CheckSat: expected unsat, got %v", result)
+	}
+}
+
+func TestPipedSolverErrors(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	err = s.DeclareConst("x", smt.IntSort)
+	if err == nil || !strings.Contains(err.Error(), "already declared") {
+		t.Errorf("DeclareConst: expected error, got %v", err)
+	}
+	if err := s.Pop(); err == nil {
+		t.Errorf("Pop: expected error with empty stack")
+	}
+	r, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"get-proof"}}})
+	if err != nil {
+		t.Fatalf("Command: %s", err)
+	}
+	if !smt.IsSymbol(r.(*smt.SList).List[0], "error") {
+		t.Errorf("Command: expected error response, got %s", r)
+	}
+
+	// the solver is still usable after errors
+	if _, err := s.CheckSat(); err != nil {
+		t.Errorf("CheckSat: %s", err)
+	}
+}
+
+func TestPipedSolverCrash(t *testing.T) {
+	s, err := newFakeSolver(t, "crash")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	if _, err := s.CheckSat(); err == nil {
+		t.Fatalf("CheckSat: expected error from crashed solver")
+	}
+	if err := s.Assert(smt.NewConst("true")); err == nil {
+		t.Errorf("Assert: expected error after crash")
+	}
+}
+
+func TestPipedSolverNoPrintSuccess(t *testing.T) {
+	s, err := newFakeSolver(t, "no-print-success")
+	if err == nil {
+		s.Close()
+		t.Fatalf("expected NewPipedSolver to fail")
+	}
+}
+
+func TestPipedSolverMissing(t *testing.T) {
+	if _, err := NewPipedSolver("/nonexistent/solver"); err == nil {
+		t.Fatalf("expected NewPipedSolver to fail")
+	}
+}
+
+func TestPipedSolverOptions(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	if err := s.SetLogic("QF_LIA"); err != nil {
+		t.Errorf("SetLogic: %s", err)
+	}
+	if err := s.SetOption(smt.OptionRandomSeed, smt.IntValue(42)); err != nil {
+		t.Errorf("SetOption: %s", err)
+	}
+	if err := s.SetOption(smt.OptionProduceModels, smt.BoolValue(true)); err != nil {
+		t.Errorf("SetOption: %s", err)
+	}
+	if err := s.SetOption(smt.OptionPrintSuccess, smt.BoolValue(false)); err == nil {
+		t.Errorf("SetOption: expected print-success to be refused")
+	}
+	if err := s.SetInfo(smt.InfoStatus, smt.SymbolValue("sat")); err != nil {
+		t.Errorf("SetInfo: %s", err)
+	}
+
+	v, err := s.GetOption(smt.OptionRandomSeed)
+	if err != nil || v != smt.IntValue(42) {
+		t.Errorf("GetOption(random-seed): %v, %v", v, err)
+	}
+	v, err = s.GetOption(smt.OptionProduceModels)
+	if err != nil || v != smt.BoolValue(true) {
+		t.Errorf("GetOption(produce-models): %v, %v", v, err)
+	}
+	if _, err = s.GetOption(smt.OptionVerbosity); !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("GetOption(verbosity): expected ErrUnsupported, got %v", err)
+	}
+
+	name, err := s.GetInfo(smt.InfoName)
+	if err != nil || name.String() != `"fake"` {
+		t.Errorf("GetInfo(name): %v, %v", name, err)
+	}
+	if _, err = s.GetInfo(smt.InfoAuthors); !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("GetInfo(authors): expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestPipedSolverStatistics(t *testing.T) {
+	s, err := newFakeSolver(t, "unknown")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	if reason := s.ReasonUnknown(); reason != "" {
+		t.Errorf("ReasonUnknown before check-sat: %q", reason)
+	}
+	result, err := s.CheckSat()
+	if err != nil || result != smt.Unknown {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	if reason := s.ReasonUnknown(); reason != "incomplete" {
+		t.Errorf("ReasonUnknown: %q", reason)
+	}
+
+	stats, err := s.Statistics()
+	if err != nil {
+		t.Fatalf("Statistics: %s", err)
+	}
+	if n, ok := stats.Int("conflicts"); !ok || n != 3 {
+		t.Errorf("conflicts: %v", stats["conflicts"])
+	}
+	if f, ok := stats.Float("memory"); !ok || f != 19.5 {
+		t.Errorf("memory: %v", stats["memory"])
+	}
+}
+
+func TestPipedSolverConcurrent(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("x%d", i)
+			if err := s.DeclareConst(id, smt.IntSort); err != nil {
+				t.Errorf("DeclareConst(%s): %s", id, err)
+				return
+			}
+			for j := 0; j < 20; j++ {
+				if err := s.Assert(smt.GT(smt.NewConst(id), smt.NewInt(j))); err != nil {
+					t.Errorf("Assert: %s", err)
+				}
+				// each response must go to the goroutine
+				// that asked for it
+				text := fmt.Sprintf("%s-%d", id, j)
+				r, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"echo"}, &smt.SString{text}}})
+				if err != nil {
+					t.Errorf("echo: %s", err)
+				} else if str, ok := r.(*smt.SString); !ok || str.Str != text {
+					t.Errorf("echo %s: got %s", text, r)
+				}
+				if result, err := s.CheckSat(); err != nil || result != smt.Sat {
+					t.Errorf("CheckSat: %v, %v", result, err)
+				}
+				if _, err := s.GetInfo(smt.InfoName); err != nil {
+					t.Errorf("GetInfo: %s", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	model, err := s.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if len(model) != 16 {
+		t.Errorf("GetModel: expected 16 constants, got %d", len(model))
+	}
+}
+
+func TestPipedSolverValidateModel(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	x := smt.NewConst("x")
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if err := s.Assert(smt.GTE(x, smt.NewInt(0))); err != nil {
+		t.Fatalf("Assert: %s", err)
+	}
+	if err := smt.ValidateModel(s); err != nil {
+		t.Fatalf("ValidateModel: %s", err)
+	}
+
+	// the fake's models make every Int 0
+	s.Push()
+	bad := smt.GT(x, smt.NewInt(0))
+	if err := s.Assert(bad); err != nil {
+		t.Fatalf("Assert: %s", err)
+	}
+	err = smt.ValidateModel(s)
+	if verr, ok := err.(*smt.ValidationError); !ok || len(verr.Failures) != 1 || verr.Failures[0].Term != bad {
+		t.Fatalf("expected a *ValidationError for %s, not %v", smt.TermToSexp(bad), err)
+	}
+	if err := s.Pop(); err != nil {
+		t.Fatalf("Pop: %s", err)
+	}
+	if err := smt.ValidateModel(s); err != nil {
+		t.Fatalf("ValidateModel after Pop: %s", err)
+	}
+
+	// assertions made through Command are tracked too
+	if _, err := s.Command(smt.CommandToSexp(&smt.Assert{bad})); err != nil {
+		t.Fatalf("Command(assert): %s", err)
+	}
+	if n := len(s.Assertions()); n != 2 {
+		t.Fatalf("expected 2 assertions, not %d", n)
+	}
+	if _, err := s.Command(smt.CommandToSexp(&smt.ResetAssertions{})); err != nil {
+		t.Fatalf("Command(reset-assertions): %s", err)
+	}
+	if n := len(s.Assertions()); n != 0 {
+		t.Fatalf("expected no assertions after reset-assertions, not %d", n)
+	}
+}
+
+func TestReadModel(t *testing.T) {
+	sexp, err := smt.NewParser(strings.NewReader(`(
+  (define-fun x () Int 1)
+  (define-fun a () (Array Int Int) ((as const (Array Int Int)) 0))
+)`)).Next()
+	if err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	model, err := readModel(sexp.(*smt.SList).List)
+	if err != nil {
+		t.Fatalf("readModel: %s", err)
+	}
+	if _, ok := model["a"]; ok {
+		t.Errorf("readModel: expected unparsable a to be left out: %v", model)
+	}
+	if x, ok := model["x"].(*smt.Int); !ok || x.Int != 1 {
+		t.Errorf("readModel: x = %v", model["x"])
+	}
+}
+
+func TestPipedSolverValidatePartialModel(t *testing.T) {
+	s, err := newFakeSolver(t, "sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	arrSort := &smt.SortApp{"Array", []smt.Sort{smt.IntSort, smt.IntSort}}
+	s.DeclareConst("arr", arrSort)
+	s.DeclareConst("x", smt.IntSort)
+	s.DeclareConst("elim", smt.IntSort)
+	x, arr, elim := smt.NewConst("x"), smt.NewConst("arr"), smt.NewConst("elim")
+	s.Assert(smt.GTE(x, smt.NewInt(0)))
+	// arr's value can't be parsed, so this can't be checked
+	s.Assert(smt.Equals(smt.NewApp("select", arr, x), smt.NewInt(0)))
+	// elim is left out of the model, but get-value gives it
+	s.Assert(smt.Equals(elim, x))
+
+	model, err := s.GetModel()
+	if err != nil {
+		t.Fatalf("GetModel: %s", err)
+	}
+	if _, ok := model["arr"]; ok {
+		t.Fatalf("expected arr to be left out of the model: %v", model)
+	}
+	if err := smt.ValidateModel(s); err != nil {
+		t.Fatalf("ValidateModel: %s", err)
+	}
+
+	// values from get-value are checked like the rest
+	bad := smt.GT(elim, x)
+	s.Assert(bad)
+	err = smt.ValidateModel(s)
+	if verr, ok := err.(*smt.ValidationError); !ok || len(verr.Failures) != 1 || verr.Failures[0].Term != bad {
+		t.Fatalf("expected a *ValidationError for %s, not %v", smt.TermToSexp(bad), err)
+	}
+}
@@ -0,0 +1,194 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bpowers/go-smt"
+)
+
+// ErrPoolClosed is returned by Pool.Get after the pool is closed.
+var ErrPoolClosed = errors.New("solver pool closed")
+
+// PoolOptions configures a Pool.
+type PoolOptions struct {
+	// New starts a solver, like NewZ3.
+	New func() (smt.Solver, error)
+	// Size is the number of solvers kept running.
+	Size int
+	// MaxQueries is the number of check-sats a solver may run
+	// before it is replaced, or 0 for no limit.
+	MaxQueries int
+	// MaxLifetime is how long a solver may run before it is
+	// replaced, or 0 for no limit.
+	MaxLifetime time.Duration
+}
+
+// Pool keeps a number of solvers running, so that independent
+// queries don't each pay to start one.  Solvers are returned to the
+// pool with (reset), which clears their assertions, declarations,
+// logic, options and info, as piped solvers restore print-success
+// and their backend's quirks after a reset.  Solvers that
+// crash, whether in use or idle, or that reach MaxQueries or
+// MaxLifetime, are replaced.
+type Pool struct {
+	opts PoolOptions
+	idle chan *poolEntry
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type poolEntry struct {
+	s       smt.Solver // nil if it couldn't be started
+	started time.Time
+	queries int64 // updated atomically, as solvers may be shared
+}
+
+func NewPool(opts PoolOptions) (*Pool, error) {
+	if opts.Size <= 0 {
+		return nil, fmt.Errorf("pool size must be positive, not %d", opts.Size)
+	}
+	p := &Pool{
+		opts: opts,
+		idle: make(chan *poolEntry, opts.Size),
+	}
+	for i := 0; i < opts.Size; i++ {
+		e, err := p.start()
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.idle <- e
+	}
+	return p, nil
+}
+
+func (p *Pool) start() (*poolEntry, error) {
+	s, err := p.opts.New()
+	if err != nil {
+		return &poolEntry{}, err
+	}
+	return &poolEntry{s: s, started: time.Now()}, nil
+}
+
+func (p *Pool) expired(e *poolEntry) bool {
+	if p.opts.MaxQueries > 0 && atomic.LoadInt64(&e.queries) >= int64(p.opts.MaxQueries) {
+		return true
+	}
+	return p.opts.MaxLifetime > 0 && time.Since(e.started) >= p.opts.MaxLifetime
+}
+
+// Get returns a solver from the pool, waiting for one if they are
+// all in use.  Closing it returns it to the pool.
+func (p *Pool) Get() (*PooledSolver, error) {
+	e, ok := <-p.idle
+	if !ok {
+		return nil, ErrPoolClosed
+	}
+	if e.s != nil && (p.expired(e) || !alive(e.s)) {
+		e.s.Close()
+		e.s = nil
+	}
+	if e.s == nil {
+		// it was replaced, or its replacement failed to start
+		var err error
+		if e, err = p.start(); err != nil {
+			p.put(e)
+			return nil, err
+		}
+	}
+	return &PooledSolver{Solver: e.s, pool: p, entry: e}, nil
+}
+
+// alive reports whether s, which has been idle in the pool, still
+// responds to commands: it may have died since it was released.
+func alive(s smt.Solver) bool {
+	_, err := s.GetInfo(smt.InfoName)
+	return err == nil
+}
+
+// release takes back a solver, resetting it or, if it can't be
+// reused, replacing it in the background.
+func (p *Pool) release(e *poolEntry) {
+	if !p.expired(e) {
+		r, err := e.s.Command(smt.CommandToSexp(&smt.Reset{}))
+		if err == nil && isSuccess(r) {
+			p.put(e)
+			return
+		}
+	}
+	e.s.Close()
+	go func() {
+		e, _ := p.start()
+		p.put(e)
+	}()
+}
+
+// put makes e available to Get, or closes it if the pool is closed.
+func (p *Pool) put(e *poolEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		if e.s != nil {
+			e.s.Close()
+		}
+		return
+	}
+	p.idle <- e
+}
+
+// Close closes the idle solvers in the pool, and those in use as
+// they are returned.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.idle)
+	for e := range p.idle {
+		if e.s != nil {
+			e.s.Close()
+		}
+	}
+}
+
+// PooledSolver is a solver on loan from a Pool.
+type PooledSolver struct {
+	smt.Solver
+	pool  *Pool
+	entry *poolEntry
+}
+
+func (s *PooledSolver) CheckSat() (smt.Satisfiable, error) {
+	atomic.AddInt64(&s.entry.queries, 1)
+	return s.Solver.CheckSat()
+}
+
+func (s *PooledSolver) CheckSatLimited(limits smt.Limits) (smt.Satisfiable, string, error) {
+	atomic.AddInt64(&s.entry.queries, 1)
+	return s.Solver.CheckSatLimited(limits)
+}
+
+func (s *PooledSolver) Interrupt() (bool, error) {
+	if i, ok := s.Solver.(Interrupter); ok {
+		return i.Interrupt()
+	}
+	return false, nil
+}
+
+// Close returns the solver to its pool.  It must not be used
+// afterwards.
+func (s *PooledSolver) Close() {
+	if s.entry == nil {
+		return
+	}
+	e := s.entry
+	s.entry, s.Solver = nil, nil
+	s.pool.release(e)
+}
@@ -0,0 +1,207 @@
+package solver
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bpowers/go-smt"
+)
+
+func newFakePool(t *testing.T, mode string, opts PoolOptions) *Pool {
+	t.Setenv(fakeSolverEnv, mode)
+	opts.New = func() (smt.Solver, error) {
+		return NewPipedSolver(os.Args[0], "-test.run=^TestHelperProcess$")
+	}
+	p, err := NewPool(opts)
+	if err != nil {
+		t.Fatalf("NewPool: %s", err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+// getSolver gets a solver from p, and checks that it is clean.
+func getSolver(t *testing.T, p *Pool) *PooledSolver {
+	s, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst on pooled solver: %s", err)
+	}
+	return s
+}
+
+func TestPool(t *testing.T) {
+	p := newFakePool(t, "sat", PoolOptions{Size: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := p.Get()
+			if err != nil {
+				t.Errorf("Get: %s", err)
+				return
+			}
+			defer s.Close()
+			if err := s.DeclareConst("x", smt.IntSort); err != nil {
+				t.Errorf("DeclareConst on pooled solver: %s", err)
+			}
+			if result, err := s.CheckSat(); err != nil || result != smt.Sat {
+				t.Errorf("CheckSat: %v, %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// solvers are reused, not replaced
+	s1 := getSolver(t, p)
+	s2 := getSolver(t, p)
+	used := map[smt.Solver]bool{s1.Solver: true, s2.Solver: true}
+	s1.Close()
+	s2.Close()
+	s1.Close() // a second Close does nothing
+	s3 := getSolver(t, p)
+	if !used[s3.Solver] {
+		t.Errorf("expected a solver to be reused")
+	}
+	s3.Close()
+
+	p.Close()
+	if _, err := p.Get(); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Get after Close: %v", err)
+	}
+}
+
+func TestPoolMaxQueries(t *testing.T) {
+	p := newFakePool(t, "sat", PoolOptions{Size: 1, MaxQueries: 2})
+
+	s := getSolver(t, p)
+	first := s.Solver
+	s.CheckSat()
+	s.Close()
+
+	s = getSolver(t, p)
+	if s.Solver != first {
+		t.Errorf("expected solver to be reused after one query")
+	}
+	s.CheckSat()
+	s.Close()
+
+	s = getSolver(t, p)
+	if s.Solver == first {
+		t.Errorf("expected solver to be replaced after two queries")
+	}
+	s.Close()
+}
+
+func TestPoolMaxLifetime(t *testing.T) {
+	p := newFakePool(t, "sat", PoolOptions{Size: 1, MaxLifetime: time.Millisecond})
+
+	s := getSolver(t, p)
+	first := s.Solver
+	s.Close()
+	time.Sleep(2 * time.Millisecond)
+
+	s = getSolver(t, p)
+	if s.Solver == first {
+		t.Errorf("expected expired solver to be replaced")
+	}
+	s.Close()
+}
+
+func TestPoolCrash(t *testing.T) {
+	p := newFakePool(t, "crash", PoolOptions{Size: 1})
+
+	s := getSolver(t, p)
+	first := s.Solver
+	if _, err := s.CheckSat(); err == nil {
+		t.Fatalf("CheckSat: expected crash")
+	}
+	s.Close()
+
+	// the crashed solver is replaced
+	s = getSolver(t, p)
+	if s.Solver == first {
+		t.Errorf("expected crashed solver to be replaced")
+	}
+	s.Close()
+}
+
+func TestPoolStartError(t *testing.T) {
+	_, err := NewPool(PoolOptions{
+		Size: 1,
+		New: func() (smt.Solver, error) {
+			return nil, errors.New("no solver")
+		},
+	})
+	if err == nil {
+		t.Errorf("expected NewPool to fail")
+	}
+	if _, err := NewPool(PoolOptions{}); err == nil {
+		t.Errorf("expected NewPool to fail without a size")
+	}
+}
+
+func TestPoolIdleCrash(t *testing.T) {
+	p := newFakePool(t, "sat", PoolOptions{Size: 1})
+
+	s := getSolver(t, p)
+	first := s.Solver
+	s.Close()
+
+	// kill the solver while it is idle in the pool
+	proc := first.(*solver).conn.(*pipeConn).cmd.Process
+	if err := proc.Kill(); err != nil {
+		t.Fatalf("Kill: %s", err)
+	}
+	proc.Wait()
+
+	s = getSolver(t, p)
+	if s.Solver == first {
+		t.Errorf("expected dead idle solver to be replaced")
+	}
+	if result, err := s.CheckSat(); err != nil || result != smt.Sat {
+		t.Errorf("CheckSat: %v, %v", result, err)
+	}
+	s.Close()
+}
+
+func TestPoolReset(t *testing.T) {
+	p := newFakePool(t, "sat", PoolOptions{Size: 1})
+
+	s := getSolver(t, p)
+	first := s.Solver
+	if err := s.SetLogic("QF_LIA"); err != nil {
+		t.Fatalf("SetLogic: %s", err)
+	}
+	if err := s.SetOption(smt.OptionRandomSeed, smt.IntValue(7)); err != nil {
+		t.Fatalf("SetOption: %s", err)
+	}
+	s.Close()
+
+	// the next borrower gets the same solver, with nothing left
+	// over from the last
+	s, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	defer s.Close()
+	if s.Solver != first {
+		t.Fatalf("expected solver to be reused")
+	}
+	if err := s.SetLogic("QF_BV"); err != nil {
+		t.Errorf("SetLogic after reuse: %s", err)
+	}
+	if v, err := s.GetOption(smt.OptionRandomSeed); err == nil {
+		t.Errorf("GetOption: expected random-seed to be reset, got %v", v)
+	}
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Errorf("DeclareConst after reuse: %s", err)
+	}
+}
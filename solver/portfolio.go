@@ -0,0 +1,414 @@
+package solver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bpowers/go-smt"
+)
+
+// Interrupter is implemented by solvers whose check-sat can be
+// abandoned from another goroutine, like piped solvers.  Interrupt
+// reports whether there was a check-sat in progress to interrupt.
+type Interrupter interface {
+	Interrupt() (bool, error)
+}
+
+// interruptRetry is how often a Portfolio tries again to interrupt a
+// solver whose check-sat hadn't started yet.
+const interruptRetry = time.Millisecond
+
+// Portfolio is a solver that runs each query on several solvers at
+// once, answering with whichever is first to give a definitive
+// result.  Everything else is forwarded to each of them.
+//
+// A solver that fails at something the others manage, like a
+// theory it doesn't implement, or that crashes, is out of step with
+// the rest and is dropped from the portfolio.  When every solver
+// fails, the first error is returned and none are dropped.
+type Portfolio struct {
+	mu      sync.Mutex
+	members []smt.Solver
+	winner  smt.Solver
+	reason  string
+	// interrupting tracks the goroutines interrupting the losers
+	// of the last check-sat, which must finish before the next
+	// starts, lest they interrupt it instead.
+	interrupting sync.WaitGroup
+}
+
+var _ smt.Solver = &Portfolio{}
+
+func NewPortfolio(solvers ...smt.Solver) *Portfolio {
+	return &Portfolio{members: solvers}
+}
+
+// Solvers returns the solvers still in the portfolio.
+func (p *Portfolio) Solvers() []smt.Solver {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]smt.Solver(nil), p.members...)
+}
+
+// Winner returns the name of the solver that answered the last
+// check-sat, or "" if none gave a definitive result.
+func (p *Portfolio) Winner() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.winner == nil {
+		return ""
+	}
+	return p.winner.Capabilities().Name
+}
+
+// each calls f on every member, dropping those it fails for unless
+// it fails for all.
+func (p *Portfolio) each(f func(s smt.Solver) error) error {
+	if len(p.members) == 0 {
+		return fmt.Errorf("portfolio: no solvers")
+	}
+	var live []smt.Solver
+	var firstErr error
+	for _, s := range p.members {
+		if err := f(s); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		live = append(live, s)
+	}
+	if len(live) == 0 {
+		return firstErr
+	}
+	for _, s := range p.members {
+		if !containsSolver(live, s) {
+			s.Close()
+		}
+	}
+	p.members = live
+	if p.winner != nil && !containsSolver(live, p.winner) {
+		p.winner = nil
+	}
+	return nil
+}
+
+func containsSolver(solvers []smt.Solver, s smt.Solver) bool {
+	for _, t := range solvers {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Portfolio) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.members {
+		s.Close()
+	}
+	p.members = nil
+}
+
+func (p *Portfolio) DeclareConst(id string, sort smt.Sort) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.DeclareConst(id, sort)
+	})
+}
+
+func (p *Portfolio) Assert(t smt.Term) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.Assert(t)
+	})
+}
+
+func (p *Portfolio) CheckSat() (smt.Satisfiable, error) {
+	result, _, err := p.CheckSatLimited(smt.Limits{})
+	return result, err
+}
+
+type portfolioResult struct {
+	i      int
+	s      smt.Solver
+	result smt.Satisfiable
+	reason string
+	err    error
+}
+
+// CheckSatLimited runs check-sat on every solver, returning as soon
+// as one is sat or unsat and interrupting the rest.  Solvers that
+// can't be interrupted keep running, and later calls wait for them.
+func (p *Portfolio) CheckSatLimited(limits smt.Limits) (smt.Satisfiable, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.winner, p.reason = nil, ""
+	if len(p.members) == 0 {
+		return smt.Unknown, "", fmt.Errorf("portfolio: no solvers")
+	}
+	p.interrupting.Wait()
+
+	results := make(chan portfolioResult, len(p.members))
+	done := make([]chan struct{}, len(p.members))
+	for i, s := range p.members {
+		done[i] = make(chan struct{})
+		go func(i int, s smt.Solver) {
+			result, reason, err := s.CheckSatLimited(limits)
+			close(done[i])
+			results <- portfolioResult{i, s, result, reason, err}
+		}(i, s)
+	}
+
+	var failed []portfolioResult
+	var reasons []string
+	for range p.members {
+		r := <-results
+		if r.err != nil {
+			failed = append(failed, r)
+			continue
+		}
+		if r.result != smt.Unknown {
+			p.winner = r.s
+			p.interrupt(r.i, done)
+			p.drop(failed)
+			return r.result, "", nil
+		}
+		if r.reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", r.s.Capabilities().Name, r.reason))
+		}
+	}
+	if len(failed) == len(p.members) {
+		// report the first member's error, whichever failed first
+		sort.Slice(failed, func(i, j int) bool { return failed[i].i < failed[j].i })
+		return smt.Unknown, "", failed[0].err
+	}
+	p.drop(failed)
+	sort.Strings(reasons)
+	p.reason = strings.Join(reasons, "; ")
+	return smt.Unknown, p.reason, nil
+}
+
+// interrupt interrupts the check-sat of every member but the
+// winner, in the background, retrying until each has started or
+// finished.
+func (p *Portfolio) interrupt(winner int, done []chan struct{}) {
+	for i, s := range p.members {
+		in, ok := s.(Interrupter)
+		if !ok || i == winner {
+			continue
+		}
+		p.interrupting.Add(1)
+		go func(in Interrupter, done chan struct{}) {
+			defer p.interrupting.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if ok, err := in.Interrupt(); ok || err != nil {
+					return
+				}
+				select {
+				case <-done:
+					return
+				case <-time.After(interruptRetry):
+				}
+			}
+		}(in, done[i])
+	}
+}
+
+// drop removes the solvers whose check-sat failed.
+func (p *Portfolio) drop(failed []portfolioResult) {
+	for _, r := range failed {
+		for i, s := range p.members {
+			if s == r.s {
+				s.Close()
+				p.members = append(p.members[:i:i], p.members[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (p *Portfolio) ReasonUnknown() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reason
+}
+
+// Statistics returns the statistics of the solver that won the last
+// check-sat, or else of the first solver.
+func (p *Portfolio) Statistics() (smt.Statistics, error) {
+	s, err := p.answerer()
+	if err != nil {
+		return nil, err
+	}
+	return s.Statistics()
+}
+
+// answerer returns the solver to answer queries about the last
+// check-sat: its winner, or else the first solver.
+func (p *Portfolio) answerer() (smt.Solver, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.winner != nil {
+		return p.winner, nil
+	}
+	if len(p.members) == 0 {
+		return nil, fmt.Errorf("portfolio: no solvers")
+	}
+	return p.members[0], nil
+}
+
+func (p *Portfolio) GetModel() (map[string]smt.Term, error) {
+	p.mu.Lock()
+	winner := p.winner
+	p.mu.Unlock()
+	if winner == nil {
+		return nil, fmt.Errorf("portfolio: no model, as no solver was sat")
+	}
+	return winner.GetModel()
+}
+
+func (p *Portfolio) Assertions() []smt.Term {
+	s, err := p.answerer()
+	if err != nil {
+		return nil
+	}
+	return s.Assertions()
+}
+
+// Push drops members that fail to push, as it can't report errors.
+// If every member fails, none are dropped, and the next call fails.
+func (p *Portfolio) Push() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.each(push)
+}
+
+// push calls s.Push, returning the error that piped solvers panic
+// with, so that one failing member needn't take down the rest.
+func push(s smt.Solver) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("push: %v", r)
+		}
+	}()
+	s.Push()
+	return nil
+}
+
+func (p *Portfolio) Pop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.Pop()
+	})
+}
+
+func (p *Portfolio) SetLogic(logic string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.SetLogic(logic)
+	})
+}
+
+func (p *Portfolio) SetOption(opt smt.Option, value smt.OptionValue) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.SetOption(opt, value)
+	})
+}
+
+func (p *Portfolio) GetOption(opt smt.Option) (smt.OptionValue, error) {
+	s, err := p.answerer()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetOption(opt)
+}
+
+func (p *Portfolio) SetInfo(info smt.Info, value smt.OptionValue) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.each(func(s smt.Solver) error {
+		return s.SetInfo(info, value)
+	})
+}
+
+func (p *Portfolio) GetInfo(info smt.Info) (smt.Sexp, error) {
+	s, err := p.answerer()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInfo(info)
+}
+
+// Capabilities combines those of every solver: the portfolio
+// supports whatever any of them does.
+func (p *Portfolio) Capabilities() smt.Capabilities {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	caps := smt.Capabilities{
+		Name:     "portfolio",
+		Logics:   []string{},
+		Theories: []smt.Theory{},
+		Features: []smt.Feature{},
+	}
+	for _, s := range p.members {
+		c := s.Capabilities()
+		caps.Logics = union(caps.Logics, c.Logics)
+		caps.Theories = union(caps.Theories, c.Theories)
+		caps.Features = union(caps.Features, c.Features)
+	}
+	return caps
+}
+
+// union returns the elements of either a or b, or nil, meaning
+// unknown, if either is.
+func union[T comparable](a, b []T) []T {
+	if a == nil || b == nil {
+		return nil
+	}
+	for _, x := range b {
+		found := false
+		for _, y := range a {
+			found = found || x == y
+		}
+		if !found {
+			a = append(a, x)
+		}
+	}
+	return a
+}
+
+// Command sends sexp to every solver, returning the response of the
+// winner of the last check-sat, or else of the first solver.
+func (p *Portfolio) Command(sexp smt.Sexp) (smt.Sexp, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	responses := make(map[smt.Solver]smt.Sexp)
+	err := p.each(func(s smt.Solver) error {
+		r, err := s.Command(sexp)
+		responses[s] = r
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.winner != nil {
+		return responses[p.winner], nil
+	}
+	return responses[p.members[0]], nil
+}
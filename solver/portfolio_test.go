@@ -0,0 +1,241 @@
+package solver
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bpowers/go-smt"
+	"github.com/bpowers/go-smt/smttest"
+)
+
+func namedFake(name string) *smttest.Solver {
+	s := smttest.NewSolver()
+	s.Caps = smt.Capabilities{Name: name}
+	return s
+}
+
+func TestPortfolio(t *testing.T) {
+	fast, slow := namedFake("fast"), namedFake("slow")
+	release := make(chan struct{})
+	defer close(release)
+	slow.CheckSatFunc = func([]smt.Term) (smt.Satisfiable, error) {
+		<-release
+		return smt.Unknown, nil
+	}
+	fast.QueueModel(map[string]smt.Term{"x": smt.NewInt(1)})
+
+	p := NewPortfolio(fast, slow)
+	if err := p.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	x := smt.GT(smt.NewConst("x"), smt.NewInt(0))
+	if err := p.Assert(x); err != nil {
+		t.Fatalf("Assert: %s", err)
+	}
+	fast.ExpectAsserted(t, x, 0)
+	slow.ExpectAsserted(t, x, 0)
+
+	// returns without waiting for slow
+	result, err := p.CheckSat()
+	if err != nil || result != smt.Sat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	if p.Winner() != "fast" {
+		t.Errorf("Winner: %q", p.Winner())
+	}
+	model, err := p.GetModel()
+	if err != nil || model["x"].(*smt.Int).Int != 1 {
+		t.Errorf("GetModel: %v, %v", model, err)
+	}
+}
+
+func TestPortfolioDrop(t *testing.T) {
+	z3ish, cvc5ish := namedFake("z3ish"), namedFake("cvc5ish")
+	z3ish.Caps.Theories = []smt.Theory{smt.TheorySeq}
+	cvc5ish.Caps.Theories = []smt.Theory{smt.TheorySeq, smt.TheorySets}
+
+	p := NewPortfolio(z3ish, cvc5ish)
+	caps := p.Capabilities()
+	if !caps.SupportsTheory(smt.TheorySets) || caps.Supports(smt.FeatureProofs) != true {
+		t.Errorf("Capabilities: %#v", caps)
+	}
+
+	if err := p.DeclareConst("s", smt.SetSort(smt.IntSort)); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if solvers := p.Solvers(); len(solvers) != 1 || solvers[0] != cvc5ish {
+		t.Errorf("expected z3ish to be dropped: %v", solvers)
+	}
+	if !z3ish.Closed() {
+		t.Errorf("expected dropped solver to be closed")
+	}
+
+	// an error from every solver drops none
+	if err := p.DeclareConst("s", smt.IntSort); err == nil {
+		t.Errorf("DeclareConst: expected error for redeclaration")
+	}
+	if len(p.Solvers()) != 1 {
+		t.Errorf("expected no solvers to be dropped")
+	}
+
+	cvc5ish.CheckSatFunc = func([]smt.Term) (smt.Satisfiable, error) {
+		return smt.Unknown, errors.New("crashed")
+	}
+	if _, err := p.CheckSat(); err == nil {
+		t.Errorf("CheckSat: expected error")
+	}
+}
+
+func TestPortfolioUnknown(t *testing.T) {
+	a, b := namedFake("a"), namedFake("b")
+	a.QueueCheckSat(smt.Unknown)
+	a.UnknownReason = "timeout"
+	b.QueueCheckSat(smt.Unknown)
+	b.UnknownReason = "incomplete"
+
+	p := NewPortfolio(a, b)
+	result, reason, err := p.CheckSatLimited(smt.Limits{ResourceLimit: 5})
+	if err != nil || result != smt.Unknown {
+		t.Fatalf("CheckSatLimited: %v, %v", result, err)
+	}
+	if reason != "a: timeout; b: incomplete" || p.ReasonUnknown() != reason {
+		t.Errorf("reason: %q", reason)
+	}
+	if p.Winner() != "" {
+		t.Errorf("Winner: %q", p.Winner())
+	}
+	if _, err := p.GetModel(); err == nil {
+		t.Errorf("GetModel: expected error without a winner")
+	}
+	if a.Limits()[0].ResourceLimit != 5 || b.Limits()[0].ResourceLimit != 5 {
+		t.Errorf("expected limits to be passed on")
+	}
+}
+
+func TestPortfolioInterrupt(t *testing.T) {
+	hang, err := newFakeBackend(t, "hang", &Backend{Name: "hang"}, nil)
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	quick, err := newFakeBackend(t, "unsat", &Backend{Name: "quick"}, nil)
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	p := NewPortfolio(hang, quick)
+	defer p.Close()
+
+	result, err := p.CheckSat()
+	if err != nil || result != smt.Unsat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	if p.Winner() != "quick" {
+		t.Errorf("Winner: %q", p.Winner())
+	}
+
+	// hang was interrupted rather than killed, and is still usable
+	if err := p.DeclareConst("x", smt.IntSort); err != nil {
+		t.Fatalf("DeclareConst: %s", err)
+	}
+	if len(p.Solvers()) != 2 {
+		t.Errorf("expected both solvers to remain: %v", p.Solvers())
+	}
+}
+
+// lateInterrupter is a fake whose Interrupt never finds a check-sat
+// to interrupt, and which notes being interrupted during its second.
+type lateInterrupter struct {
+	*smttest.Solver
+	mu     sync.Mutex
+	second bool
+	late   bool
+}
+
+func (s *lateInterrupter) Interrupt() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.late = s.late || s.second
+	return false, nil
+}
+
+func TestPortfolioInterruptLate(t *testing.T) {
+	winner := namedFake("winner")
+	loser := &lateInterrupter{Solver: namedFake("loser")}
+	release := make(chan struct{})
+	checks := 0
+	loser.CheckSatFunc = func([]smt.Term) (smt.Satisfiable, error) {
+		checks++
+		if checks == 1 {
+			<-release
+		} else {
+			loser.mu.Lock()
+			loser.second = true
+			loser.mu.Unlock()
+			time.Sleep(5 * interruptRetry)
+		}
+		return smt.Unknown, nil
+	}
+
+	// the second check-sat has no winner, so nothing should be
+	// interrupted during it
+	winner.QueueCheckSat(smt.Sat, smt.Unknown)
+
+	p := NewPortfolio(winner, loser)
+	if result, err := p.CheckSat(); err != nil || result != smt.Sat {
+		t.Fatalf("CheckSat: %v, %v", result, err)
+	}
+	// the loser finishes while its interrupt is being retried
+	time.Sleep(2 * interruptRetry)
+	close(release)
+	if result, err := p.CheckSat(); err != nil || result != smt.Unknown {
+		t.Fatalf("second CheckSat: %v, %v", result, err)
+	}
+	loser.mu.Lock()
+	defer loser.mu.Unlock()
+	if loser.late {
+		t.Errorf("loser interrupted during a later check-sat")
+	}
+}
+
+func TestPortfolioAllFail(t *testing.T) {
+	first, second := namedFake("first"), namedFake("second")
+	release := make(chan struct{})
+	first.CheckSatFunc = func([]smt.Term) (smt.Satisfiable, error) {
+		<-release
+		return smt.Unknown, errors.New("first failed")
+	}
+	second.CheckSatFunc = func([]smt.Term) (smt.Satisfiable, error) {
+		defer close(release)
+		return smt.Unknown, errors.New("second failed")
+	}
+
+	// the first member's error is reported, though it failed last
+	p := NewPortfolio(first, second)
+	if _, err := p.CheckSat(); err == nil || err.Error() != "first failed" {
+		t.Errorf("CheckSat: expected first's error, got %v", err)
+	}
+	if len(p.Solvers()) != 2 {
+		t.Errorf("expected no solvers to be dropped")
+	}
+}
+
+// brokenPush is a fake whose Push panics, as a piped solver's does
+// when the solver has crashed.
+type brokenPush struct {
+	*smttest.Solver
+}
+
+func (s brokenPush) Push() {
+	panic("Command: broken pipe")
+}
+
+func TestPortfolioPushFails(t *testing.T) {
+	ok, broken := namedFake("ok"), brokenPush{namedFake("broken")}
+	p := NewPortfolio(ok, broken)
+	p.Push()
+	if solvers := p.Solvers(); len(solvers) != 1 || solvers[0] != ok {
+		t.Errorf("expected broken to be dropped: %v", solvers)
+	}
+	ok.ExpectLevel(t, 1)
+}
@@ -0,0 +1,187 @@
+package solver
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bpowers/go-smt"
+)
+
+// transcriptConn copies commands and responses to w: each command is
+// followed by its response on a line starting with "; ", or by an
+// error on a line starting with ";! ".
+type transcriptConn struct {
+	conn conn
+	w    io.Writer
+}
+
+func (c *transcriptConn) roundTrip(cmd smt.Sexp) (smt.Sexp, error) {
+	fmt.Fprintf(c.w, "%s\n", cmd)
+	r, err := c.conn.roundTrip(cmd)
+	if err != nil {
+		fmt.Fprintf(c.w, ";! %s\n", commentLines(err.Error()))
+	} else {
+		fmt.Fprintf(c.w, "; %s\n", commentLines(r.String()))
+	}
+	return r, err
+}
+
+func (c *transcriptConn) signal(sig os.Signal) error {
+	return c.conn.signal(sig)
+}
+
+func (c *transcriptConn) close() error {
+	return c.conn.close()
+}
+
+// commentLines makes s safe to put in a comment, by continuing it
+// on to following comment lines at any newlines.
+func commentLines(s string) string {
+	return strings.Replace(s, "\n", "\n;| ", -1)
+}
+
+type transcriptEntry struct {
+	cmd      string
+	response smt.Sexp
+	err      string
+}
+
+// NewReplaySolver returns a solver that, rather than running a
+// solver process, answers commands with the responses recorded in a
+// transcript written by a piped solver (see Options.Transcript).
+// Commands must be issued in the same order they were recorded in;
+// any other command is an error.
+func NewReplaySolver(r io.Reader) (smt.Solver, error) {
+	entries, err := readTranscript(r)
+	if err != nil {
+		return nil, err
+	}
+	return newSolver(&Backend{Name: "replay"}, &replayConn{entries: entries}, nil)
+}
+
+func readTranscript(r io.Reader) ([]transcriptEntry, error) {
+	var entries []transcriptEntry
+	var cmd, response []string
+	isErr := false
+
+	flush := func() error {
+		if len(cmd) == 0 {
+			return nil
+		}
+		text := strings.Join(cmd, "\n")
+		sexp, err := smt.NewParser(strings.NewReader(text)).Next()
+		if err != nil {
+			return fmt.Errorf("transcript command '%s': %s", text, err)
+		}
+		e := transcriptEntry{cmd: sexp.String()}
+		if len(response) == 0 {
+			return fmt.Errorf("transcript command '%s' has no response", text)
+		}
+		if isErr {
+			e.err = strings.Join(response, "\n")
+		} else {
+			text := strings.Join(response, "\n")
+			if e.response, err = smt.NewParser(strings.NewReader(text)).Next(); err != nil {
+				return fmt.Errorf("transcript response '%s': %s", text, err)
+			}
+		}
+		entries = append(entries, e)
+		cmd, response, isErr = nil, nil, false
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case len(cmd) > 0 && len(response) == 0 && unterminated(strings.Join(cmd, "\n")):
+			// a command may span several lines, if it contains
+			// a multi-line string or quoted symbol, which may
+			// have lines that look like responses
+			cmd = append(cmd, line)
+		case strings.HasPrefix(line, ";| ") && len(response) > 0:
+			response = append(response, line[3:])
+		case strings.HasPrefix(line, "; ") && len(cmd) > 0 && len(response) == 0:
+			response = append(response, line[2:])
+		case strings.HasPrefix(line, ";! ") && len(cmd) > 0 && len(response) == 0:
+			response = append(response, line[3:])
+			isErr = true
+		case strings.HasPrefix(line, ";"), strings.TrimSpace(line) == "":
+			// other comments and blank lines
+		default:
+			if len(response) > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			cmd = append(cmd, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// unterminated reports whether cmd, a command as printed to a
+// transcript, is missing its end: it has unclosed parentheses, or
+// ends inside a string or quoted symbol.
+func unterminated(cmd string) bool {
+	depth := 0
+	var quote byte // '"' or '|' while inside one
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case quote != 0:
+			// "" inside a string is an escaped quote, which
+			// toggling twice handles
+			if c == quote {
+				quote = 0
+			}
+		case c == '"', c == '|':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		}
+	}
+	return quote != 0 || depth > 0
+}
+
+type replayConn struct {
+	entries []transcriptEntry
+	next    int
+}
+
+func (c *replayConn) roundTrip(cmd smt.Sexp) (smt.Sexp, error) {
+	if c.next >= len(c.entries) {
+		return nil, fmt.Errorf("replay: transcript exhausted at %s", cmd)
+	}
+	e := c.entries[c.next]
+	if s := cmd.String(); s != e.cmd {
+		return nil, fmt.Errorf("replay: expected command %s, not %s", e.cmd, s)
+	}
+	c.next++
+	if e.err != "" {
+		return nil, fmt.Errorf("%s", e.err)
+	}
+	return e.response, nil
+}
+
+// signal does nothing: a replayed solver can't be interrupted, but
+// it never runs long enough to need to be.
+func (c *replayConn) signal(sig os.Signal) error {
+	return nil
+}
+
+func (c *replayConn) close() error {
+	return nil
+}
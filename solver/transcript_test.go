@@ -0,0 +1,160 @@
+package solver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bpowers/go-smt"
+)
+
+// cannedConn answers each command with the next of its responses.
+type cannedConn struct {
+	responses []string
+}
+
+func (c *cannedConn) roundTrip(cmd smt.Sexp) (smt.Sexp, error) {
+	if len(c.responses) == 0 {
+		return nil, fmt.Errorf("no more responses")
+	}
+	r := c.responses[0]
+	c.responses = c.responses[1:]
+	return smt.NewParser(strings.NewReader(r)).Next()
+}
+
+func (c *cannedConn) signal(sig os.Signal) error {
+	return nil
+}
+
+func (c *cannedConn) close() error {
+	return nil
+}
+
+func TestTranscriptReplay(t *testing.T) {
+	var transcript bytes.Buffer
+	c := &cannedConn{responses: []string{
+		"success",
+		`(error "unsupported")`,
+		`(:version "1.0")`,
+		"success",
+		"success",
+		"sat",
+		"(model (define-fun x () Int 4))",
+		`(error "line 1
+line 2")`,
+	}}
+	s, err := newSolver(&Backend{Name: "canned"}, c, &Options{Transcript: &transcript})
+	if err != nil {
+		t.Fatalf("newSolver: %s", err)
+	}
+
+	session := func(s smt.Solver) (smt.Satisfiable, map[string]smt.Term) {
+		if err := s.DeclareConst("x", smt.IntSort); err != nil {
+			t.Fatalf("DeclareConst: %s", err)
+		}
+		if err := s.Assert(smt.GT(smt.NewConst("x"), smt.NewInt(3))); err != nil {
+			t.Fatalf("Assert: %s", err)
+		}
+		result, err := s.CheckSat()
+		if err != nil {
+			t.Fatalf("CheckSat: %s", err)
+		}
+		model, err := s.GetModel()
+		if err != nil {
+			t.Fatalf("GetModel: %s", err)
+		}
+		r, err := s.Command(&smt.SList{[]smt.Sexp{&smt.SSymbol{"get-proof"}}})
+		if err != nil || !strings.Contains(r.String(), "line 2") {
+			t.Fatalf("Command: %v %v", r, err)
+		}
+		// the transcript has run out
+		if err := s.Pop(); err == nil {
+			t.Fatalf("expected error")
+		}
+		return result, model
+	}
+
+	result, model := session(s)
+	if result != smt.Sat || model["x"].(*smt.Int).Int != 4 {
+		t.Fatalf("unexpected result %s %v", result, model)
+	}
+
+	expected := `(set-option :print-success true)
+; success
+(get-info :name)
+; (error "unsupported")
+(get-info :version)
+; (:version "1.0")
+(declare-const x Int)
+; success
+(assert (> x 3))
+; success
+(check-sat)
+; sat
+(get-model)
+; (model (define-fun x () Int 4))
+(get-proof)
+; (error "line 1
+;| line 2")
+(pop)
+;! no more responses
+`
+	if transcript.String() != expected {
+		t.Fatalf("transcript:\n%s\n!=\n%s", transcript.String(), expected)
+	}
+
+	replay, err := NewReplaySolver(strings.NewReader(expected))
+	if err != nil {
+		t.Fatalf("NewReplaySolver: %s", err)
+	}
+	rResult, rModel := session(replay)
+	if rResult != result || rModel["x"].(*smt.Int).Int != 4 {
+		t.Fatalf("replay differs: %s %v", rResult, rModel)
+	}
+
+	replay, _ = NewReplaySolver(strings.NewReader(expected))
+	if err = replay.DeclareConst("y", smt.IntSort); err == nil {
+		t.Fatalf("expected error for out of order command")
+	}
+}
+
+func TestTranscriptMultiLineCommand(t *testing.T) {
+	var transcript bytes.Buffer
+	c := &cannedConn{responses: []string{
+		"success",
+		`(:name "canned")`,
+		`(:version "1.0")`,
+		`"a
+; b"`,
+		"success",
+	}}
+	s, err := newSolver(&Backend{Name: "canned"}, c, &Options{Transcript: &transcript})
+	if err != nil {
+		t.Fatalf("newSolver: %s", err)
+	}
+
+	// a string and a quoted symbol whose continuation lines look
+	// like responses
+	echo := &smt.SList{[]smt.Sexp{&smt.SSymbol{"echo"}, &smt.SString{"a\n; b"}}}
+	session := func(s smt.Solver) {
+		r, err := s.Command(echo)
+		if err != nil {
+			t.Fatalf("echo: %s", err)
+		}
+		if str, ok := r.(*smt.SString); !ok || str.Str != "a\n; b" {
+			t.Fatalf("echo: %s", r)
+		}
+		if err := s.DeclareConst("x\n; y", smt.IntSort); err != nil {
+			t.Fatalf("DeclareConst: %s", err)
+		}
+	}
+	session(s)
+
+	replay, err := NewReplaySolver(strings.NewReader(transcript.String()))
+	if err != nil {
+		t.Fatalf("NewReplaySolver: %s\n%s", err, transcript.String())
+	}
+	session(replay)
+}
@@ -0,0 +1,66 @@
+package solver
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// watchdogGrace is how long past its timeout a solver is given to
+// give up on its own, and then how long after being interrupted it
+// is given to respond before it is killed.
+var watchdogGrace = 500 * time.Millisecond
+
+// watchdog enforces a timeout on a solver that doesn't enforce it
+// itself: first interrupting it, which most solvers take as a request
+// to abandon the current query, and then killing it.
+type watchdog struct {
+	mu    sync.Mutex
+	c     conn
+	timer *time.Timer
+	done  bool
+	fired bool
+}
+
+func startWatchdog(c conn, timeout time.Duration) *watchdog {
+	w := &watchdog{c: c}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer = time.AfterFunc(timeout+watchdogGrace, w.interrupt)
+	return w
+}
+
+func (w *watchdog) interrupt() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return
+	}
+	w.fired = true
+	if err := w.c.signal(os.Interrupt); err != nil {
+		w.c.signal(os.Kill)
+		return
+	}
+	w.timer = time.AfterFunc(watchdogGrace, w.kill)
+}
+
+func (w *watchdog) kill() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.done {
+		w.c.signal(os.Kill)
+	}
+}
+
+// stop stops the watchdog, reporting whether it had fired.  A nil
+// watchdog never fires.
+func (w *watchdog) stop() bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+	w.timer.Stop()
+	return w.fired
+}
@@ -0,0 +1,127 @@
+package solver
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bpowers/go-smt"
+)
+
+func TestLimits(t *testing.T) {
+	var transcript bytes.Buffer
+	b := &Backend{
+		Name: "fake",
+		Quirks: Quirks{
+			TimeoutOption:  "timeout",
+			ResourceOption: "rlimit",
+		},
+	}
+	s, err := newFakeBackend(t, "unknown", b, &Options{Transcript: &transcript})
+	if err != nil {
+		t.Fatalf("newFakeBackend: %s", err)
+	}
+	defer s.Close()
+
+	result, reason, err := s.CheckSatLimited(smt.Limits{Timeout: 2 * time.Second, ResourceLimit: 1000})
+	if err != nil {
+		t.Fatalf("CheckSatLimited: %s", err)
+	}
+	if result != smt.Unknown || reason != "incomplete" {
+		t.Errorf("CheckSatLimited: %v, %q", result, reason)
+	}
+	// unchanged limits aren't set again
+	if _, _, err = s.CheckSatLimited(smt.Limits{Timeout: 2 * time.Second, ResourceLimit: 1000}); err != nil {
+		t.Fatalf("CheckSatLimited: %s", err)
+	}
+	// and a plain CheckSat clears them
+	if _, err = s.CheckSat(); err != nil {
+		t.Fatalf("CheckSat: %s", err)
+	}
+
+	var options []string
+	for _, line := range strings.Split(transcript.String(), "\n") {
+		if strings.HasPrefix(line, "(set-option :") && !strings.Contains(line, "print-success") {
+			options = append(options, line)
+		}
+	}
+	expected := []string{
+		"(set-option :timeout 2000)",
+		"(set-option :rlimit 1000)",
+		"(set-option :timeout 0)",
+		"(set-option :rlimit 0)",
+	}
+	if strings.Join(options, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("options set:\n%s", strings.Join(options, "\n"))
+	}
+
+	_, _, err = s.CheckSatLimited(smt.Limits{MemoryMB: 100})
+	if !errors.Is(err, smt.ErrUnsupported) {
+		t.Errorf("CheckSatLimited: expected ErrUnsupported for memory, got %v", err)
+	}
+}
+
+func withWatchdogGrace(t *testing.T, grace time.Duration) {
+	old := watchdogGrace
+	watchdogGrace = grace
+	t.Cleanup(func() { watchdogGrace = old })
+}
+
+func TestWatchdogInterrupt(t *testing.T) {
+	withWatchdogGrace(t, 50*time.Millisecond)
+	s, err := newFakeSolver(t, "hang")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	result, reason, err := s.CheckSatLimited(smt.Limits{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("CheckSatLimited: %s", err)
+	}
+	if result != smt.Unknown || reason != "timeout" {
+		t.Errorf("CheckSatLimited: %v, %q", result, reason)
+	}
+	// the interrupted solver is still usable
+	if err := s.DeclareConst("x", smt.IntSort); err != nil {
+		t.Errorf("DeclareConst: %s", err)
+	}
+}
+
+func TestWatchdogKill(t *testing.T) {
+	withWatchdogGrace(t, 50*time.Millisecond)
+	s, err := newFakeSolver(t, "hang-hard")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	start := time.Now()
+	result, reason, err := s.CheckSatLimited(smt.Limits{Timeout: 50 * time.Millisecond})
+	if err == nil {
+		t.Fatalf("CheckSatLimited: expected error from killed solver")
+	}
+	if result != smt.Unknown || reason != "timeout" {
+		t.Errorf("CheckSatLimited: %v, %q", result, reason)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("watchdog took %s", elapsed)
+	}
+}
+
+func TestWatchdogLateAnswer(t *testing.T) {
+	withWatchdogGrace(t, 50*time.Millisecond)
+	s, err := newFakeSolver(t, "hang-sat")
+	if err != nil {
+		t.Fatalf("newFakeSolver: %s", err)
+	}
+	defer s.Close()
+
+	// an answer arriving after the interrupt is still the answer
+	result, reason, err := s.CheckSatLimited(smt.Limits{Timeout: 50 * time.Millisecond})
+	if err != nil || result != smt.Sat || reason != "" {
+		t.Fatalf("CheckSatLimited: %v, %q, %v", result, reason, err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Statistics maps the names of a solver's statistics, without their
+// leading colons, to their values: an int64 for counts, a float64
+// for measurements like time and memory, and a string for anything
+// else.
+type Statistics map[string]interface{}
+
+// Int returns the statistic name, if it is a count.
+func (s Statistics) Int(name string) (int64, bool) {
+	n, ok := s[name].(int64)
+	return n, ok
+}
+
+// Float returns the statistic name, if it is a number.
+func (s Statistics) Float(name string) (float64, bool) {
+	switch v := s[name].(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// SexpToStatistics reads statistics from a response to (get-info
+// :all-statistics): a list of alternating keywords and values.
+func SexpToStatistics(sexp Sexp) (Statistics, error) {
+	list, ok := sexp.(*SList)
+	if !ok || len(list.List)%2 != 0 {
+		return nil, fmt.Errorf("expected keyword/value list, not '%s'", sexp)
+	}
+	stats := make(Statistics)
+	for i := 0; i < len(list.List); i += 2 {
+		kw, ok := list.List[i].(*SKeyword)
+		if !ok {
+			return nil, fmt.Errorf("expected keyword, not '%s'", list.List[i])
+		}
+		switch v := list.List[i+1].(type) {
+		case *SInt:
+			stats[kw.Keyword] = v.Int
+		case *SDecimal:
+			f, err := strconv.ParseFloat(v.Decimal, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", kw.Keyword, err)
+			}
+			stats[kw.Keyword] = f
+		case *SString:
+			stats[kw.Keyword] = v.Str
+		case *SSymbol:
+			stats[kw.Keyword] = v.Symbol
+		default:
+			stats[kw.Keyword] = v.String()
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// TermManager hash-conses terms: every structurally identical term
+// built by (or interned into) the same TermManager is the same
+// pointer.  Managed terms can be compared with == and used directly
+// as map keys, and shared subterms are visible to the printer as
+// shared pointers.  Terms are never released, so a TermManager
+// should live only as long as the formulas built with it, and
+// interned terms must not be modified.
+//
+// A TermManager is safe for concurrent use.
+type TermManager struct {
+	mu    sync.Mutex
+	terms map[termKey]Term
+	ids   map[Term]uint64
+}
+
+type termKind byte
+
+const (
+	kindString termKind = iota
+	kindInt
+	kindBitVec
+	kindConst
+	kindApp
+	kindIndexedApp
+	kindLet
+	kindAs
+	kindDecimal
+	kindForall
+	kindExists
+	kindAnnotated
+	kindBigInt
+)
+
+// termKey uniquely describes a term whose children have already
+// been interned; children are referred to by their IDs.
+type termKey struct {
+	kind termKind
+	id   string
+	a, b int64
+	rest string
+}
+
+func NewTermManager() *TermManager {
+	return &TermManager{
+		terms: make(map[termKey]Term),
+		ids:   make(map[Term]uint64),
+	}
+}
+
+// ID returns the unique, dense identifier of a term interned in tm.
+// It can be used as an O(1) hash of the term's structure.
+func (tm *TermManager) ID(t Term) (uint64, bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	id, ok := tm.ids[t]
+	return id, ok
+}
+
+// Len returns the number of distinct terms in tm.
+func (tm *TermManager) Len() int {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return len(tm.ids)
+}
+
+// Intern returns the canonical version of t, interning it and all of
+// its subterms as necessary.
+func (tm *TermManager) Intern(t Term) Term {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.intern(t)
+}
+
+func (tm *TermManager) NewInt(i int) Term {
+	return tm.Intern(&Int{int64(i)})
+}
+
+func (tm *TermManager) NewBool(b bool) Term {
+	return tm.Intern(NewBool(b))
+}
+
+func (tm *TermManager) NewBitVec(n, width int64) Term {
+	return tm.Intern(&BitVec{n, width})
+}
+
+func (tm *TermManager) NewConst(s string) Term {
+	return tm.Intern(&Const{Identifier(s)})
+}
+
+func (tm *TermManager) NewApp(x string, args ...Term) Term {
+	return tm.Intern(&App{Identifier(x), args})
+}
+
+func (tm *TermManager) NewLet(id string, value, in Term) Term {
+	return tm.Intern(&Let{Identifier(id), value, in})
+}
+
+func (tm *TermManager) intern(term Term) Term {
+	if _, ok := tm.ids[term]; ok {
+		return term
+	}
+
+	var key termKey
+	var canon Term
+	switch t := term.(type) {
+	case *String:
+		key = termKey{kind: kindString, id: t.String}
+		canon = t
+	case *Int:
+		key = termKey{kind: kindInt, a: t.Int}
+		canon = t
+	case *BigInt:
+		key = termKey{kind: kindBigInt, id: t.Int.String()}
+		canon = t
+	case *BitVec:
+		key = termKey{kind: kindBitVec, a: t.Value, b: t.Width}
+		canon = t
+	case *Const:
+		key = termKey{kind: kindConst, id: string(t.Id)}
+		canon = t
+	case *App:
+		args, enc := tm.internArgs(t.Args)
+		key = termKey{kind: kindApp, id: string(t.Id), rest: enc}
+		canon = &App{t.Id, args}
+	case *IndexedApp:
+		args, enc := tm.internArgs(t.Args)
+		var buf []byte
+		for _, i := range t.Indices {
+			buf = binary.AppendVarint(buf, i)
+		}
+		key = termKey{kind: kindIndexedApp, id: string(t.Id), a: int64(len(t.Indices)), rest: string(buf) + enc}
+		canon = &IndexedApp{t.Id, t.Indices, args}
+	case *Let:
+		args, enc := tm.internArgs([]Term{t.Value, t.In})
+		key = termKey{kind: kindLet, id: string(t.Id), rest: enc}
+		canon = &Let{t.Id, args[0], args[1]}
+	case *As:
+		key = termKey{kind: kindAs, id: string(t.Id), rest: SortToSexp(t.Sort).String()}
+		canon = t
+	case *Decimal:
+		key = termKey{kind: kindDecimal, id: t.Decimal}
+		canon = t
+	case *Forall:
+		args, enc := tm.internArgs([]Term{t.Body})
+		key = termKey{kind: kindForall, id: sortedVarsToSexp(t.Vars).String(), rest: enc}
+		canon = &Forall{t.Vars, args[0]}
+	case *Exists:
+		args, enc := tm.internArgs([]Term{t.Body})
+		key = termKey{kind: kindExists, id: sortedVarsToSexp(t.Vars).String(), rest: enc}
+		canon = &Exists{t.Vars, args[0]}
+	case *Annotated:
+		args, enc := tm.internArgs([]Term{t.Term})
+		attrs := &SList{attributesToSexps(t.Attributes)}
+		key = termKey{kind: kindAnnotated, id: attrs.String(), rest: enc}
+		canon = &Annotated{args[0], t.Attributes}
+	default:
+		panic("unreachable")
+	}
+
+	if existing, ok := tm.terms[key]; ok {
+		return existing
+	}
+	tm.terms[key] = canon
+	tm.ids[canon] = uint64(len(tm.ids))
+	return canon
+}
+
+// internArgs interns each argument, returning the canonical
+// arguments along with their IDs packed into a string suitable for
+// use in a termKey.
+func (tm *TermManager) internArgs(args []Term) ([]Term, string) {
+	canon := make([]Term, len(args))
+	buf := make([]byte, 0, len(args)*binary.MaxVarintLen64)
+	for i, arg := range args {
+		canon[i] = tm.intern(arg)
+		buf = binary.AppendUvarint(buf, tm.ids[canon[i]])
+	}
+	return canon, string(buf)
+}
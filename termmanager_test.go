@@ -0,0 +1,53 @@
+package smt
+
+import (
+	"testing"
+)
+
+func TestTermManager(t *testing.T) {
+	tm := NewTermManager()
+
+	build := func() Term {
+		x := NewConst("x")
+		shared := Add(Mul(x, NewInt(2)), Divisible(3, x))
+		return And(Equals(shared, NewInt(4)), LT(shared, SeqLen(SeqEmpty(IntSort))))
+	}
+
+	a := tm.Intern(build())
+	b := tm.Intern(build())
+	if a != b {
+		t.Fatalf("structurally equal terms not pointer-equal")
+	}
+	if tm.NewApp("and", a.(*App).Args...) != a {
+		t.Fatalf("NewApp didn't return the interned term")
+	}
+
+	eq := a.(*App).Args[0].(*App)
+	lt := a.(*App).Args[1].(*App)
+	if eq.Args[0] != lt.Args[0] {
+		t.Fatalf("shared subterm not shared")
+	}
+	if tm.NewConst("x") != eq.Args[0].(*App).Args[0].(*App).Args[0] {
+		t.Fatalf("leaf not shared")
+	}
+
+	idA, ok := tm.ID(a)
+	if !ok {
+		t.Fatalf("interned term has no ID")
+	}
+	if idC, _ := tm.ID(tm.Intern(Or(NewConst("x"), NewConst("y")))); idC == idA {
+		t.Fatalf("distinct terms have the same ID")
+	}
+	if _, ok := tm.ID(build()); ok {
+		t.Fatalf("uninterned term has an ID")
+	}
+
+	n := tm.Len()
+	tm.Intern(build())
+	if tm.Len() != n {
+		t.Fatalf("re-interning grew the table: %d != %d", tm.Len(), n)
+	}
+	if tm.Intern(Divisible(4, NewConst("x"))) == tm.Intern(Divisible(3, NewConst("x"))) {
+		t.Fatalf("indices ignored")
+	}
+}
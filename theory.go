@@ -0,0 +1,132 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupported is returned (possibly wrapped) when a solver is
+// asked to do something its backend doesn't implement.
+var ErrUnsupported = errors.New("unsupported by solver")
+
+// Theory names a family of sorts and operators that only some
+// backends implement.  Theories every solver is expected to handle
+// (Core, Ints, BitVecs) aren't named.
+type Theory string
+
+const (
+	TheorySeq  Theory = "Seq"
+	TheorySets Theory = "Sets"
+)
+
+var theoryPrefixes = []struct {
+	prefix string
+	theory Theory
+}{
+	{"seq.", TheorySeq},
+	{"set.", TheorySets},
+	{"rel.", TheorySets},
+	{"tuple.", TheorySets},
+}
+
+// theoryIds maps operators that aren't named by a prefix.
+var theoryIds = map[Identifier]Theory{
+	"tuple": TheorySets,
+}
+
+var theorySorts = map[Identifier]Theory{
+	"Seq":      TheorySeq,
+	"Set":      TheorySets,
+	"Relation": TheorySets,
+	"Tuple":    TheorySets,
+}
+
+// TermTheories returns the extension theories whose operators or
+// sorts appear in t, in sorted order.
+func TermTheories(t Term) []Theory {
+	seen := make(map[Theory]bool)
+	termTheories(seen, t)
+	return sortedTheories(seen)
+}
+
+// SortTheories returns the extension theories that sort s belongs
+// to, in sorted order.
+func SortTheories(s Sort) []Theory {
+	seen := make(map[Theory]bool)
+	sortTheories(seen, s)
+	return sortedTheories(seen)
+}
+
+func sortedTheories(seen map[Theory]bool) []Theory {
+	var theories []Theory
+	for th := range seen {
+		theories = append(theories, th)
+	}
+	sort.Slice(theories, func(i, j int) bool { return theories[i] < theories[j] })
+	return theories
+}
+
+func idTheories(seen map[Theory]bool, id Identifier) {
+	if th, ok := theoryIds[id]; ok {
+		seen[th] = true
+	}
+	for _, p := range theoryPrefixes {
+		if strings.HasPrefix(string(id), p.prefix) {
+			seen[p.theory] = true
+		}
+	}
+}
+
+func termTheories(seen map[Theory]bool, term Term) {
+	switch t := term.(type) {
+	case *App:
+		idTheories(seen, t.Id)
+		for _, arg := range t.Args {
+			termTheories(seen, arg)
+		}
+	case *IndexedApp:
+		idTheories(seen, t.Id)
+		for _, arg := range t.Args {
+			termTheories(seen, arg)
+		}
+	case *Let:
+		termTheories(seen, t.Value)
+		termTheories(seen, t.In)
+	case *Annotated:
+		termTheories(seen, t.Term)
+	case *As:
+		idTheories(seen, t.Id)
+		sortTheories(seen, t.Sort)
+	case *Forall:
+		for _, v := range t.Vars {
+			sortTheories(seen, v.Sort)
+		}
+		termTheories(seen, t.Body)
+	case *Exists:
+		for _, v := range t.Vars {
+			sortTheories(seen, v.Sort)
+		}
+		termTheories(seen, t.Body)
+	}
+}
+
+func sortTheories(seen map[Theory]bool, sort Sort) {
+	switch s := sort.(type) {
+	case *SortName:
+		if th, ok := theorySorts[s.Id]; ok {
+			seen[th] = true
+		}
+	case *SortApp:
+		if th, ok := theorySorts[s.Id]; ok {
+			seen[th] = true
+		}
+		for _, arg := range s.Args {
+			sortTheories(seen, arg)
+		}
+	}
+}
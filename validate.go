@@ -0,0 +1,258 @@
+// Copyright 2016 Bobby Powers. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package smt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AssertionStack tracks the terms asserted at each level of a
+// solver's assertion stack.  The zero value is an empty stack, at
+// level 0.
+type AssertionStack struct {
+	levels [][]Term
+}
+
+func (s *AssertionStack) top() int {
+	if len(s.levels) == 0 {
+		s.levels = [][]Term{nil}
+	}
+	return len(s.levels) - 1
+}
+
+// Assert adds t to the current level.
+func (s *AssertionStack) Assert(t Term) {
+	i := s.top()
+	s.levels[i] = append(s.levels[i], t)
+}
+
+// Push starts a new level.
+func (s *AssertionStack) Push() {
+	s.top()
+	s.levels = append(s.levels, nil)
+}
+
+// Pop discards the current level and its assertions.
+func (s *AssertionStack) Pop() error {
+	if s.top() == 0 {
+		return fmt.Errorf("pop: empty stack")
+	}
+	s.levels = s.levels[:len(s.levels)-1]
+	return nil
+}
+
+// Reset discards every assertion, returning to level 0.
+func (s *AssertionStack) Reset() {
+	s.levels = nil
+}
+
+// Level returns the number of levels pushed.
+func (s *AssertionStack) Level() int {
+	return s.top()
+}
+
+// At returns the terms asserted at level n.
+func (s *AssertionStack) At(n int) []Term {
+	if n < 0 || n > s.top() {
+		return nil
+	}
+	return append([]Term(nil), s.levels[n]...)
+}
+
+// Terms returns every term asserted, outermost level first.
+func (s *AssertionStack) Terms() []Term {
+	var all []Term
+	for _, level := range s.levels {
+		all = append(all, level...)
+	}
+	return all
+}
+
+// Track updates the stack for cmd, once a solver has executed it
+// successfully: asserts, pushes, pops and resets change it, and
+// other commands are ignored.
+func (s *AssertionStack) Track(cmd Command) error {
+	switch c := cmd.(type) {
+	case *Assert:
+		s.Assert(c.Term)
+	case *Push:
+		for i := 0; i < c.Levels; i++ {
+			s.Push()
+		}
+	case *Pop:
+		if c.Levels > s.Level() {
+			return fmt.Errorf("pop %d: only %d levels", c.Levels, s.Level())
+		}
+		s.levels = s.levels[:len(s.levels)-c.Levels]
+	case *Reset, *ResetAssertions:
+		s.Reset()
+	}
+	return nil
+}
+
+// AssertionFailure describes an assertion a model doesn't satisfy:
+// either it evaluated to Value rather than true, or evaluating it
+// failed with Err.
+type AssertionFailure struct {
+	Term  Term
+	Value Term
+	Err   error
+}
+
+func (f AssertionFailure) String() string {
+	if f.Err != nil {
+		return fmt.Sprintf("%s: %s", TermToSexp(f.Term), f.Err)
+	}
+	return fmt.Sprintf("%s is %s", TermToSexp(f.Term), TermToSexp(f.Value))
+}
+
+// ValidationError is returned when a model doesn't satisfy the
+// assertions it was checked against.
+type ValidationError struct {
+	Failures []AssertionFailure
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.String()
+	}
+	return fmt.Sprintf("model fails %d assertions: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// CheckModel evaluates each of assertions under model with Eval,
+// and returns a *ValidationError listing those that aren't true.
+// Solvers leave constants whose values don't matter, or that they
+// eliminated, out of models, so assertions mentioning a constant
+// with no value in model are skipped.
+func CheckModel(model map[string]Term, assertions []Term) error {
+	var failures []AssertionFailure
+	for _, t := range assertions {
+		if len(missing(model, []Term{t})) > 0 {
+			continue
+		}
+		v, err := Eval(t, model)
+		if err != nil {
+			failures = append(failures, AssertionFailure{Term: t, Err: err})
+		} else if !IsSymbol(TermToSexp(v), "true") {
+			failures = append(failures, AssertionFailure{Term: t, Value: v})
+		}
+	}
+	if len(failures) > 0 {
+		return &ValidationError{failures}
+	}
+	return nil
+}
+
+// missing returns the free constants of terms with no value in
+// model, in order of first appearance.
+func missing(model map[string]Term, terms []Term) []Identifier {
+	var ids []Identifier
+	seen := make(map[Identifier]bool)
+	var walk func(term Term, bound map[Identifier]bool)
+	walk = func(term Term, bound map[Identifier]bool) {
+		switch t := term.(type) {
+		case *Const:
+			if _, ok := model[string(t.Id)]; !ok && !bound[t.Id] && !seen[t.Id] &&
+				t.Id != "true" && t.Id != "false" {
+				seen[t.Id] = true
+				ids = append(ids, t.Id)
+			}
+		case *App:
+			for _, arg := range t.Args {
+				walk(arg, bound)
+			}
+		case *IndexedApp:
+			for _, arg := range t.Args {
+				walk(arg, bound)
+			}
+		case *Annotated:
+			walk(t.Term, bound)
+		case *Let:
+			walk(t.Value, bound)
+			walk(t.In, with(bound, t.Id))
+		case *Forall:
+			walk(t.Body, with(bound, varIds(t.Vars)...))
+		case *Exists:
+			walk(t.Body, with(bound, varIds(t.Vars)...))
+		}
+	}
+	for _, t := range terms {
+		walk(t, nil)
+	}
+	return ids
+}
+
+func varIds(vars []SortedVar) []Identifier {
+	ids := make([]Identifier, len(vars))
+	for i, v := range vars {
+		ids[i] = v.Id
+	}
+	return ids
+}
+
+// with returns a copy of bound with ids added.
+func with(bound map[Identifier]bool, ids ...Identifier) map[Identifier]bool {
+	b := make(map[Identifier]bool, len(bound)+len(ids))
+	for id := range bound {
+		b[id] = true
+	}
+	for _, id := range ids {
+		b[id] = true
+	}
+	return b
+}
+
+// ValidateModel gets s's model and checks, in Go, that it satisfies
+// every term s has asserted, returning a *ValidationError if not.
+// Call it after a check-sat returns Sat.  Constants left out of the
+// model are asked for with get-value; those still without a value
+// we can read, like arrays, are treated as CheckModel treats them.
+func ValidateModel(s Solver) error {
+	model, err := s.GetModel()
+	if err != nil {
+		return fmt.Errorf("GetModel: %s", err)
+	}
+	if model == nil {
+		model = make(map[string]Term)
+	}
+	assertions := s.Assertions()
+	if ids := missing(model, assertions); len(ids) > 0 {
+		getValues(s, model, ids)
+	}
+	return CheckModel(model, assertions)
+}
+
+// getValues adds the values of ids to model, as far as s gives
+// them; solvers may not support get-value, or give values we can't
+// parse.
+func getValues(s Solver, model map[string]Term, ids []Identifier) {
+	terms := make([]Term, len(ids))
+	for i, id := range ids {
+		terms[i] = &Const{id}
+	}
+	r, err := s.Command(CommandToSexp(&GetValue{terms}))
+	if err != nil {
+		return
+	}
+	pairs, ok := r.(*SList)
+	if !ok {
+		return
+	}
+	for _, pair := range pairs.List {
+		p, ok := pair.(*SList)
+		if !ok || len(p.List) != 2 {
+			continue
+		}
+		id, ok := p.List[0].(*SSymbol)
+		if !ok {
+			continue
+		}
+		if v, err := SexpToTerm(p.List[1]); err == nil {
+			model[id.Symbol] = v
+		}
+	}
+}